@@ -1,17 +1,54 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/RandithaK/StudyBuddy_Backend/graph"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/auth"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/clock"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/email"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/i18n"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/jobs"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/netguard"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/pubsub"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/push"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/server"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/webhook"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/worker"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestHealthAndGetTasks(t *testing.T) {
@@ -119,3 +156,1977 @@ func TestChangePassword(t *testing.T) {
 		t.Fatalf("expected token in login response with new password: %s", rr.Body.String())
 	}
 }
+
+func TestLoginUpgradesLegacyPasswordHash(t *testing.T) {
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	r := server.SetupRouter(s)
+
+	// Store a bcrypt hash, as created before Argon2id became the default
+	legacy, err := auth.BcryptHasher{Cost: bcrypt.MinCost}.Hash("password")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	s.CreateUser(models.User{
+		ID:       "legacy-user-id",
+		Name:     "Legacy User",
+		Email:    "legacy@example.com",
+		Password: legacy,
+	})
+
+	loginBody := `{"query":"mutation Login($input: LoginInput!){ login(input:$input){ token } }","variables":{"input":{"email":"legacy@example.com","password":"password"}}}`
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(loginBody))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if !strings.Contains(rr.Body.String(), "token") {
+		t.Fatalf("expected token in login response: %s", rr.Body.String())
+	}
+
+	user, err := s.GetUser("legacy-user-id")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if !strings.HasPrefix(user.Password, "$argon2id$") {
+		t.Fatalf("expected password to be rehashed with argon2id, got %q", user.Password)
+	}
+	if ok, err := auth.VerifyPassword(user.Password, "password"); err != nil || !ok {
+		t.Fatalf("rehashed password does not verify: %v", err)
+	}
+
+	// Hashes with degenerate parameters are malformed, not verified.
+	for _, bad := range []string{
+		"$argon2id$v=19$m=65536,t=0,p=2$c2FsdHNhbHRzYWx0c2FsdA$",
+		"$argon2id$v=19$m=65536,t=1,p=0$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5",
+		"$argon2id$v=19$m=65536,t=1,p=2$$a2V5a2V5a2V5a2V5",
+		"$argon2id$v=19$m=65536,t=1,p=2$c2FsdHNhbHRzYWx0c2FsdA$",
+	} {
+		if ok, err := auth.VerifyPassword(bad, "anything"); ok || !errors.Is(err, auth.ErrMalformedHash) {
+			t.Fatalf("expected %q to be malformed, got %v %v", bad, ok, err)
+		}
+	}
+	for key, value := range map[string]string{"ARGON2_ITERATIONS": "0", "ARGON2_MEMORY_KIB": "7"} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := auth.PasswordHasherFromEnv(); err == nil {
+				t.Fatalf("expected %s=%s to be rejected", key, value)
+			}
+		})
+	}
+}
+
+// graphQL posts a GraphQL request to the router and decodes the response.
+func graphQL(t *testing.T, r http.Handler, token, query string, variables map[string]any) map[string]any {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	var resp map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response %q: %v", rr.Body.String(), err)
+	}
+	return resp
+}
+
+func TestTasksConnectionPagination(t *testing.T) {
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	server.SeedStore(s)
+	r := server.SetupRouter(s)
+	token, _ := auth.GenerateAccessToken("test-user-id")
+
+	query := `query Tasks($after: String){ tasksConnection(first: 2, after: $after, filter: {completed: false}, orderBy: {field: DUE_DATE}){ totalCount edges{ node{ id } } pageInfo{ hasNextPage endCursor } } }`
+	var ids []string
+	var after any
+	for page := 0; page < 3; page++ {
+		resp := graphQL(t, r, token, query, map[string]any{"after": after})
+		conn, ok := resp["data"].(map[string]any)["tasksConnection"].(map[string]any)
+		if !ok {
+			t.Fatalf("unexpected response: %v", resp)
+		}
+		if total := conn["totalCount"].(float64); total != 4 {
+			t.Fatalf("expected totalCount 4, got %v", total)
+		}
+		for _, e := range conn["edges"].([]any) {
+			ids = append(ids, e.(map[string]any)["node"].(map[string]any)["id"].(string))
+		}
+		info := conn["pageInfo"].(map[string]any)
+		if !info["hasNextPage"].(bool) {
+			break
+		}
+		after = info["endCursor"]
+	}
+
+	want := []string{"task-5", "task-3", "task-1", "task-4"}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected tasks %v in due date order, got %v", want, ids)
+	}
+}
+
+func TestEventsDateRangeFilter(t *testing.T) {
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	r := server.SetupRouter(s)
+	token, _ := auth.GenerateAccessToken("events-user-id")
+
+	for _, e := range []models.Event{
+		{ID: "ev-1", Title: "Lecture", StartsAt: time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC), EndsAt: time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC), Type: "CLASS", UserID: "events-user-id"},
+		{ID: "ev-2", Title: "Midterm", StartsAt: time.Date(2025, 12, 5, 13, 0, 0, 0, time.UTC), EndsAt: time.Date(2025, 12, 5, 15, 0, 0, 0, time.UTC), Type: "EXAM", UserID: "events-user-id"},
+		{ID: "ev-3", Title: "Final", StartsAt: time.Date(2025, 12, 20, 13, 0, 0, 0, time.UTC), EndsAt: time.Date(2025, 12, 20, 16, 0, 0, 0, time.UTC), Type: "EXAM", UserID: "events-user-id"},
+		{ID: "ev-4", Title: "Someone else", StartsAt: time.Date(2025, 12, 5, 13, 0, 0, 0, time.UTC), EndsAt: time.Date(2025, 12, 5, 15, 0, 0, 0, time.UTC), Type: "EXAM", UserID: "other-user"},
+	} {
+		s.CreateEvent(e)
+	}
+
+	resp := graphQL(t, r, token, `query { events(from: "2025-12-01", to: "2025-12-10", type: "EXAM"){ id } }`, nil)
+	events, ok := resp["data"].(map[string]any)["events"].([]any)
+	if !ok {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+	if len(events) != 1 || events[0].(map[string]any)["id"] != "ev-2" {
+		t.Fatalf("expected only ev-2, got %v", events)
+	}
+}
+
+func TestTaskTimestamps(t *testing.T) {
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	r := server.SetupRouter(s)
+	token, _ := auth.GenerateAccessToken("times-user-id")
+
+	resp := graphQL(t, r, token, `mutation{ createTask(input: {title: "Essay", description: "", courseId: "c1", dueDate: "2025-12-10", dueTime: "23:59", hasReminder: true}){ id dueDate dueTime dueAt } }`, nil)
+	task, ok := resp["data"].(map[string]any)["createTask"].(map[string]any)
+	if !ok {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+	if task["dueDate"] != "2025-12-10" || task["dueTime"] != "23:59" || task["dueAt"] != "2025-12-10T23:59:00Z" {
+		t.Fatalf("unexpected due fields: %v", task)
+	}
+
+	// Only tasks due within the window are picked up by the worker.
+	now := time.Now()
+	s.CreateTask(models.Task{ID: "soon", UserID: "times-user-id", DueAt: now.Add(2 * time.Hour)})
+	s.CreateTask(models.Task{ID: "later", UserID: "times-user-id", DueAt: now.Add(72 * time.Hour)})
+	s.CreateTask(models.Task{ID: "past", UserID: "times-user-id", DueAt: now.Add(-time.Hour)})
+	due, err := s.GetTasksDueIn("24h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].ID != "soon" {
+		t.Fatalf("expected only the task due soon, got %v", due)
+	}
+}
+
+func TestPerUserTimeZones(t *testing.T) {
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	r := server.SetupRouter(s)
+	s.CreateUser(models.User{ID: "colombo-user", Email: "lk@example.com", TimeZone: "Asia/Colombo"})
+	s.CreateUser(models.User{ID: "ny-user", Email: "ny@example.com"})
+	colombo, _ := auth.GenerateAccessToken("colombo-user")
+	ny, _ := auth.GenerateAccessToken("ny-user")
+
+	// 23:59 in Colombo (UTC+5:30) is 18:29 UTC, and reads back as local time.
+	resp := graphQL(t, r, colombo, `mutation{ createTask(input: {title: "Essay", description: "", courseId: "c1", dueDate: "2025-12-10", dueTime: "23:59", hasReminder: true}){ dueDate dueTime dueAt } }`, nil)
+	task, _ := resp["data"].(map[string]any)["createTask"].(map[string]any)
+	if task["dueAt"] != "2025-12-10T18:29:00Z" || task["dueDate"] != "2025-12-10" || task["dueTime"] != "23:59" {
+		t.Fatalf("unexpected due fields: %v", resp)
+	}
+
+	resp = graphQL(t, r, ny, `mutation{ updateUser(input: {timeZone: "America/New_York"}){ timeZone } }`, nil)
+	if resp["data"].(map[string]any)["updateUser"].(map[string]any)["timeZone"] != "America/New_York" {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+	if ext := errorExtensions(t, graphQL(t, r, ny, `mutation{ updateUser(input: {timeZone: "Mars/Olympus"}){ id } }`, nil)); ext["code"] != "VALIDATION_FAILED" {
+		t.Fatalf("expected invalid time zone to be rejected, got %v", ext)
+	}
+
+	// 2025-11-02 is 25 hours long in New York: clocks fall back from EDT to EST.
+	for _, e := range []struct{ title, date, start, wantStartsAt string }{
+		{"early", "2025-11-02", "00:30", "2025-11-02T04:30:00Z"},
+		{"late", "2025-11-02", "23:30", "2025-11-03T04:30:00Z"},
+		{"next day", "2025-11-03", "00:15", "2025-11-03T05:15:00Z"},
+	} {
+		resp := graphQL(t, r, ny, `mutation($input: NewEventInput!){ createEvent(input: $input){ startsAt } }`, map[string]any{
+			"input": map[string]any{"title": e.title, "date": e.date, "startTime": e.start, "endTime": e.start, "type": "CLASS"},
+		})
+		if got := resp["data"].(map[string]any)["createEvent"].(map[string]any)["startsAt"]; got != e.wantStartsAt {
+			t.Fatalf("%s: expected startsAt %s, got %v", e.title, e.wantStartsAt, got)
+		}
+	}
+	resp = graphQL(t, r, ny, `{ events(from: "2025-11-02", to: "2025-11-02"){ title date startTime } }`, nil)
+	events, _ := resp["data"].(map[string]any)["events"].([]any)
+	if len(events) != 2 || events[1].(map[string]any)["title"] != "late" || events[1].(map[string]any)["startTime"] != "23:30" {
+		t.Fatalf("expected both events on the local day, got %v", resp)
+	}
+
+	// Reminders show the due time in the user's zone.
+	due := time.Now().Add(2 * time.Hour)
+	s.CreateTask(models.Task{ID: "tz-task", Title: "Quiz", UserID: "colombo-user", DueAt: due, HasReminder: true})
+	worker.NewWorker(s).CheckUpcomingTasks(context.Background())
+	n, err := s.GetNotificationByReferenceID("tz-task", "TASK_DUE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, _ := time.LoadLocation("Asia/Colombo")
+	u, _ := s.GetUser("colombo-user")
+	if want, got := due.In(loc).Format("15:04"), i18n.For(u).Notification(n); !strings.Contains(got, want) {
+		t.Fatalf("expected reminder to mention %s, got %q", want, got)
+	}
+}
+
+func TestLocalizedNotifications(t *testing.T) {
+	// Every catalog translates every English message.
+	want := i18n.Keys(i18n.DefaultLocale)
+	for _, locale := range []string{"si", "ta"} {
+		got := map[string]bool{}
+		for _, k := range i18n.Keys(locale) {
+			got[k] = true
+		}
+		for _, k := range want {
+			if !got[k] {
+				t.Errorf("catalog %s is missing %q", locale, k)
+			}
+		}
+	}
+
+	mailer := &email.MemoryMailer{}
+	server.Mailer = mailer
+	t.Cleanup(func() { server.Mailer = nil })
+	s := store.NewInMemoryStore()
+	r := server.SetupRouter(s)
+
+	// Notifications are stored as a type and parameters and rendered in the
+	// reader's locale and zone when read.
+	prefs := models.DefaultPreferences()
+	prefs.Locale = "si-LK"
+	s.CreateUser(models.User{ID: "si-user", Email: "si@example.com", TimeZone: "Asia/Colombo", Preferences: &prefs})
+	due := time.Now().Add(2 * time.Hour)
+	s.CreateTask(models.Task{ID: "si-task", Title: "Quiz", UserID: "si-user", DueAt: due, HasReminder: true})
+	worker.NewWorker(s).CheckUpcomingTasks(context.Background())
+	n, err := s.GetNotificationByReferenceID("si-task", "TASK_DUE")
+	if err != nil || n.Message != "" || n.Params["title"] != "Quiz" {
+		t.Fatalf("expected a notification with parameters, got %+v %v", n, err)
+	}
+	s.CreateNotification(models.Notification{UserID: "si-user", Message: "Course 'CS50' was updated.", Type: "INFO"})
+
+	token, _ := auth.GenerateAccessToken("si-user")
+	loc, _ := time.LoadLocation("Asia/Colombo")
+	local := due.In(loc).Format("2006-01-02 15:04")
+	messages := func() map[string]bool {
+		resp := graphQL(t, r, token, `{ notifications{ message } }`, nil)
+		res := map[string]bool{}
+		for _, n := range resp["data"].(map[string]any)["notifications"].([]any) {
+			res[n.(map[string]any)["message"].(string)] = true
+		}
+		return res
+	}
+	for locale, want := range map[string]string{
+		"si-LK": "'Quiz' කාර්යය " + local + " ට නියමිතයි.",
+		"ta":    "'Quiz' பணி " + local + " அன்று முடிக்கப்பட வேண்டும்.",
+		"fr":    "Task 'Quiz' is due " + due.In(loc).Format("Mon 2 Jan at 15:04") + ".",
+	} {
+		graphQL(t, r, token, `mutation($l: String!){ updatePreferences(input: {locale: $l}){ locale } }`, map[string]any{"l": locale})
+		got := messages()
+		if !got[want] || !got["Course 'CS50' was updated."] {
+			t.Errorf("%s: expected %q and the stored message, got %v", locale, want, got)
+		}
+	}
+
+	// Emails use the same catalogs, starting with the one sent on signup.
+	graphQL(t, r, "", `mutation{ register(input: {name: "Nila", email: "nila@example.com", password: "correct horse battery", locale: "ta"}){ token } }`, nil)
+	for i := 0; i < 100 && len(mailer.Sent()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	sent := mailer.Sent()
+	if len(sent) != 1 || sent[0].Subject != "உங்கள் மின்னஞ்சலைச் சரிபார்க்கவும்" ||
+		!strings.Contains(sent[0].Text, "உங்களுக்கு StudyBuddy கணக்கு இருப்பதால்") {
+		t.Fatalf("expected a Tamil verification email, got %+v", sent)
+	}
+	if err := email.SendNotificationEmail(context.Background(), mailer, "si@example.com", "si", "පරීක්ෂණය"); err != nil {
+		t.Fatal(err)
+	}
+	if sent = mailer.Sent(); sent[1].Subject != "ඔබට නොකියවූ දැනුම්දීමක් ඇත" || !strings.Contains(sent[1].HTML, "මෙම ඊමේල් ලැබීම නවත්වන්න") {
+		t.Fatalf("expected a Sinhala notification email, got %+v", sent[1])
+	}
+}
+
+func TestUserPreferences(t *testing.T) {
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	r := server.SetupRouter(s)
+	s.CreateUser(models.User{ID: "prefs-user", Email: "prefs@example.com", IsVerified: true, TimeZone: "Asia/Colombo"})
+	token, _ := auth.GenerateAccessToken("prefs-user")
+
+	const fields = `taskReminderLeadMinutes emailDelayMinutes quietHours{ start end } channels weekStart locale`
+	resp := graphQL(t, r, token, `{ me{ preferences{ `+fields+` } } }`, nil)
+	prefs := resp["data"].(map[string]any)["me"].(map[string]any)["preferences"].(map[string]any)
+	if prefs["taskReminderLeadMinutes"] != float64(1440) || prefs["weekStart"] != "MONDAY" || prefs["quietHours"] != nil {
+		t.Fatalf("unexpected default preferences: %v", prefs)
+	}
+
+	resp = graphQL(t, r, token, `mutation{ updatePreferences(input: {taskReminderLeadMinutes: 60, quietHours: {start: "22:00", end: "07:00"}, channels: [IN_APP], weekStart: SUNDAY}){ `+fields+` } }`, nil)
+	prefs = resp["data"].(map[string]any)["updatePreferences"].(map[string]any)
+	if prefs["taskReminderLeadMinutes"] != float64(60) || prefs["emailDelayMinutes"] != float64(60) || prefs["weekStart"] != "SUNDAY" ||
+		prefs["quietHours"].(map[string]any)["start"] != "22:00" || len(prefs["channels"].([]any)) != 1 {
+		t.Fatalf("unexpected updated preferences: %v", resp)
+	}
+	ext := errorExtensions(t, graphQL(t, r, token, `mutation{ updatePreferences(input: {eventReminderLeadMinutes: -5, locale: "English"}){ locale } }`, nil))
+	if fields, _ := ext["fields"].(map[string]any); ext["code"] != "VALIDATION_FAILED" || fields["eventReminderLeadMinutes"] == nil || fields["locale"] == nil {
+		t.Fatalf("expected invalid preferences to be rejected, got %v", ext)
+	}
+	resp = graphQL(t, r, token, `mutation{ updatePreferences(input: {quietHours: null}){ quietHours{ start } taskReminderLeadMinutes } }`, nil)
+	if prefs = resp["data"].(map[string]any)["updatePreferences"].(map[string]any); prefs["quietHours"] != nil || prefs["taskReminderLeadMinutes"] != float64(60) {
+		t.Fatalf("expected only quiet hours to be cleared, got %v", resp)
+	}
+
+	// Only the task due within the user's 60 minute lead gets a reminder.
+	s.CreateTask(models.Task{ID: "soon", Title: "Soon", UserID: "prefs-user", DueAt: time.Now().Add(30 * time.Minute), HasReminder: true})
+	s.CreateTask(models.Task{ID: "later", Title: "Later", UserID: "prefs-user", DueAt: time.Now().Add(2 * time.Hour), HasReminder: true})
+	worker.NewWorker(s).CheckUpcomingTasks(context.Background())
+	if _, err := s.GetNotificationByReferenceID("soon", "TASK_DUE"); err != nil {
+		t.Fatalf("expected a reminder for the task due soon: %v", err)
+	}
+	if _, err := s.GetNotificationByReferenceID("later", "TASK_DUE"); err == nil {
+		t.Fatal("expected no reminder for the task outside the lead time")
+	}
+
+	// 23:00 in Colombo falls in quiet hours of 22:00 to 07:00.
+	user, _ := s.GetUser("prefs-user")
+	p := user.Prefs()
+	p.Channels = []string{models.ChannelEmail}
+	p.QuietHours = &models.QuietHours{Start: 22 * 60, End: 7 * 60}
+	user.Preferences = &p
+	n := models.Notification{CreatedAt: time.Date(2025, 12, 10, 17, 0, 0, 0, time.UTC)}
+	if send, skip := worker.EmailFallback(user, n, time.Date(2025, 12, 10, 17, 30, 0, 0, time.UTC)); send || skip {
+		t.Fatal("expected email to be held back during quiet hours")
+	}
+	if send, _ := worker.EmailFallback(user, n, time.Date(2025, 12, 11, 2, 0, 0, 0, time.UTC)); !send {
+		t.Fatal("expected email without an in-app channel to be sent after quiet hours")
+	}
+	p.Channels = []string{models.ChannelInApp}
+	if _, skip := worker.EmailFallback(user, n, time.Date(2025, 12, 11, 2, 0, 0, 0, time.UTC)); !skip {
+		t.Fatal("expected email to be skipped when the email channel is off")
+	}
+}
+
+func TestRemindersAndSnooze(t *testing.T) {
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	r := server.SetupRouter(s)
+	s.CreateUser(models.User{ID: "reminder-user", Email: "remind@example.com"})
+	s.CreateUser(models.User{ID: "other-user", Email: "other@example.com"})
+	token, _ := auth.GenerateAccessToken("reminder-user")
+	other, _ := auth.GenerateAccessToken("other-user")
+
+	resp := graphQL(t, r, token, `mutation{ createTask(input: {title: "Essay", description: "", courseId: "c1", dueDate: "2030-01-10", dueTime: "09:00", hasReminder: true}){ reminders{ minutesBefore at } } }`, nil)
+	reminders := resp["data"].(map[string]any)["createTask"].(map[string]any)["reminders"].([]any)
+	if len(reminders) != 1 || reminders[0].(map[string]any)["minutesBefore"] != float64(1440) {
+		t.Fatalf("expected the default reminder, got %v", resp)
+	}
+	resp = graphQL(t, r, token, `mutation{ createTask(input: {title: "Essay", description: "", courseId: "c1", dueDate: "2030-01-10", dueTime: "09:00", hasReminder: true, reminders: [{minutesBefore: 60}, {at: "2030-01-09T18:00:00Z"}]}){ reminders{ minutesBefore at } } }`, nil)
+	reminders = resp["data"].(map[string]any)["createTask"].(map[string]any)["reminders"].([]any)
+	if len(reminders) != 2 || reminders[1].(map[string]any)["at"] != "2030-01-09T18:00:00Z" || reminders[1].(map[string]any)["minutesBefore"] != nil {
+		t.Fatalf("unexpected reminders: %v", resp)
+	}
+	ext := errorExtensions(t, graphQL(t, r, token, `mutation{ createTask(input: {title: "Essay", description: "", courseId: "c1", dueDate: "2030-01-10", dueTime: "09:00", hasReminder: true, reminders: [{minutesBefore: 60, at: "2030-01-09T18:00:00Z"}]}){ id } }`, nil))
+	if fields, _ := ext["fields"].(map[string]any); fields["reminders[0]"] == nil {
+		t.Fatalf("expected a reminder with both fields to be rejected, got %v", ext)
+	}
+
+	// The two hour reminder has fired and the one hour reminder has not.
+	s.CreateTask(models.Task{ID: "remind-task", Title: "Quiz", CourseID: "c1", UserID: "reminder-user", DueAt: time.Now().Add(90 * time.Minute), HasReminder: true,
+		Reminders: []models.Reminder{{Before: 2 * time.Hour}, {Before: time.Hour}}})
+	w := worker.NewWorker(s)
+	// A scan out of time stops before creating anything.
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.CheckUpcomingTasks(done); !errors.Is(err, context.Canceled) || len(s.GetNotifications("reminder-user", false)) != 0 {
+		t.Fatalf("expected a cancelled scan to stop, got %v", err)
+	}
+	w.CheckUpcomingTasks(context.Background())
+	w.CheckUpcomingTasks(context.Background())
+	if got := len(s.GetNotifications("reminder-user", false)); got != 1 {
+		t.Fatalf("expected one reminder, got %d", got)
+	}
+	resp = graphQL(t, r, token, `mutation{ updateTask(input: {id: "remind-task", reminders: [{minutesBefore: 120}, {minutesBefore: 100}]}){ id } }`, nil)
+	if resp["errors"] != nil {
+		t.Fatalf("unexpected errors: %v", resp)
+	}
+	w.CheckUpcomingTasks(context.Background())
+	notifications := s.GetNotifications("reminder-user", false)
+	if len(notifications) != 2 {
+		t.Fatalf("expected the new 100 minute reminder to fire once, got %d notifications", len(notifications))
+	}
+
+	id := notifications[0].ID
+	if ext := errorExtensions(t, graphQL(t, r, other, `mutation($id: ID!){ snoozeNotification(id: $id, minutes: 10){ id } }`, map[string]any{"id": id})); ext["code"] != "FORBIDDEN" {
+		t.Fatalf("expected snoozing another user's notification to be forbidden, got %v", ext)
+	}
+	if ext := errorExtensions(t, graphQL(t, r, other, `mutation($id: ID!){ markNotificationAsRead(id: $id) }`, map[string]any{"id": id})); ext["code"] != "FORBIDDEN" {
+		t.Fatalf("expected reading another user's notification to be forbidden, got %v", ext)
+	}
+	if got := len(s.GetNotifications("reminder-user", true)); got != 2 {
+		t.Fatalf("expected the notifications to stay unread, got %d unread", got)
+	}
+	resp = graphQL(t, r, token, `mutation($id: ID!){ snoozeNotification(id: $id, minutes: 10){ read snoozedUntil } }`, map[string]any{"id": id})
+	if n := resp["data"].(map[string]any)["snoozeNotification"].(map[string]any); n["read"] != true || n["snoozedUntil"] == nil {
+		t.Fatalf("unexpected snoozed notification: %v", resp)
+	}
+	w.CheckSnoozedNotifications(context.Background())
+	if got := len(s.GetNotifications("reminder-user", true)); got != 1 {
+		t.Fatalf("expected the snoozed notification to wait, got %d unread", got)
+	}
+	s.SnoozeNotification(id, time.Now().Add(-time.Minute))
+	w.CheckSnoozedNotifications(context.Background())
+	w.CheckSnoozedNotifications(context.Background())
+	unread := s.GetNotifications("reminder-user", true)
+	if len(unread) != 2 || unread[0].Message != notifications[0].Message {
+		t.Fatalf("expected the snoozed notification to be sent again once, got %v", unread)
+	}
+}
+
+func TestJobQueue(t *testing.T) {
+	if got := []time.Duration{jobs.ExponentialBackoff(1), jobs.ExponentialBackoff(3), jobs.ExponentialBackoff(20)}; got[0] != 30*time.Second || got[1] != 2*time.Minute || got[2] != time.Hour {
+		t.Fatalf("unexpected backoff: %v", got)
+	}
+
+	s := store.NewInMemoryStore()
+	q := jobs.New(s)
+	q.Backoff = func(int) time.Duration { return 0 }
+	q.MaxAttempts = 3
+	calls := 0
+	q.Handle("flaky", func(_ context.Context, j models.Job) error {
+		if calls++; calls < 3 {
+			return fmt.Errorf("attempt %d failed", j.Attempts)
+		}
+		return nil
+	})
+	q.Handle("broken", func(context.Context, models.Job) error { panic("boom") })
+	q.Enqueue("flaky", "flaky-1", nil, time.Now())
+	q.Enqueue("flaky", "flaky-1", nil, time.Now())
+	q.Enqueue("broken", "", map[string]string{"n": "1"}, time.Now())
+	q.Enqueue("later", "", nil, time.Now().Add(time.Hour))
+	for i := 0; i < 5; i++ {
+		q.RunDue(context.Background(), 10)
+	}
+	if calls != 3 {
+		t.Fatalf("expected the flaky job to run three times, ran %d", calls)
+	}
+	if pending, _ := s.ListJobs(models.JobPending, 10); len(pending) != 1 || pending[0].Kind != "later" {
+		t.Fatalf("expected only the future job to be pending, got %v", pending)
+	}
+	dead, _ := s.ListJobs(models.JobDead, 10)
+	if len(dead) != 1 || dead[0].Attempts != 3 || !strings.Contains(dead[0].LastError, "boom") {
+		t.Fatalf("expected the broken job to be dead-lettered, got %v", dead)
+	}
+
+	// A job whose lease ran out belongs to whoever claimed it next.
+	start := time.Now()
+	s.EnqueueJob(models.Job{Kind: "slow", RunAt: start})
+	first, _ := s.ClaimJobs(start, time.Minute, 1)
+	second, _ := s.ClaimJobs(start.Add(2*time.Minute), time.Minute, 1)
+	if len(first) != 1 || len(second) != 1 || second[0].ID != first[0].ID {
+		t.Fatalf("expected the expired job to be claimed again, got %v %v", first, second)
+	}
+	for _, err := range []error{s.CompleteJob(first[0]), s.RescheduleJob(first[0], start, "late"), s.KillJob(first[0], "late")} {
+		if !errors.Is(err, store.ErrJobLost) {
+			t.Fatalf("expected the first claim to be lost, got %v", err)
+		}
+	}
+	if err := s.CompleteJob(second[0]); err != nil {
+		t.Fatalf("expected the second claim to complete the job: %v", err)
+	}
+
+	t.Setenv("ADMIN_EMAILS", "ops@example.com")
+	r := server.SetupRouter(s)
+	s.CreateUser(models.User{ID: "admin-user", Email: "ops@example.com", IsVerified: true})
+	s.CreateUser(models.User{ID: "plain-user", Email: "plain@example.com", IsVerified: true})
+	admin, _ := auth.GenerateAccessToken("admin-user")
+	plain, _ := auth.GenerateAccessToken("plain-user")
+	if ext := errorExtensions(t, graphQL(t, r, plain, `{ failedJobs{ id } }`, nil)); ext["code"] != "FORBIDDEN" {
+		t.Fatalf("expected failed jobs to be admin only, got %v", ext)
+	}
+	resp := graphQL(t, r, admin, `{ failedJobs{ id kind status attempts lastError } }`, nil)
+	failed, _ := resp["data"].(map[string]any)["failedJobs"].([]any)
+	if len(failed) != 1 || failed[0].(map[string]any)["status"] != "DEAD" || failed[0].(map[string]any)["kind"] != "broken" {
+		t.Fatalf("unexpected failed jobs: %v", resp)
+	}
+	resp = graphQL(t, r, admin, `mutation($id: ID!){ retryJob(id: $id){ status attempts } }`, map[string]any{"id": dead[0].ID})
+	if job := resp["data"].(map[string]any)["retryJob"].(map[string]any); job["status"] != "PENDING" || job["attempts"] != float64(0) {
+		t.Fatalf("unexpected retried job: %v", resp)
+	}
+
+	// A tick scans for due emails and sends them as jobs.
+	s.CreateUser(models.User{ID: "email-user", Email: "email@example.com", IsVerified: true,
+		Preferences: &models.Preferences{Channels: []string{models.ChannelEmail}}})
+	n := s.CreateNotification(models.Notification{UserID: "email-user", Message: "Task 'Essay' is due soon.", Type: "TASK_DUE"})
+	worker.NewWorker(s).Tick()
+	if n, _ = s.GetNotification(n.ID); !n.Emailed {
+		t.Fatal("expected the notification to be emailed by a job")
+	}
+}
+
+func TestWorkerLease(t *testing.T) {
+	s := store.NewInMemoryStore()
+	if ok, _ := s.AcquireLease("worker", "a", time.Minute); !ok {
+		t.Fatal("expected the first holder to acquire the lease")
+	}
+	if ok, _ := s.AcquireLease("worker", "a", time.Minute); !ok {
+		t.Fatal("expected the holder to renew its lease")
+	}
+	if ok, _ := s.AcquireLease("worker", "b", time.Minute); ok {
+		t.Fatal("expected a second holder to be refused while the lease is held")
+	}
+	s.ReleaseLease("worker", "b")
+	if ok, _ := s.AcquireLease("worker", "b", time.Minute); ok {
+		t.Fatal("expected a release by another holder to be ignored")
+	}
+	s.ReleaseLease("worker", "a")
+	if ok, _ := s.AcquireLease("worker", "b", time.Millisecond); !ok {
+		t.Fatal("expected the lease to be free once released")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if ok, _ := s.AcquireLease("worker", "a", time.Minute); !ok {
+		t.Fatal("expected an expired lease to be taken over")
+	}
+
+	// Only the worker holding the lease runs a cycle.
+	s = store.NewInMemoryStore()
+	s.CreateUser(models.User{ID: "lease-user", Email: "lease@example.com", IsVerified: true,
+		Preferences: &models.Preferences{Channels: []string{models.ChannelEmail}}})
+	n := s.CreateNotification(models.Notification{UserID: "lease-user", Message: "Task 'Essay' is due soon.", Type: "TASK_DUE"})
+	s.AcquireLease("worker", "other-instance", time.Minute)
+	worker.NewWorker(s).Tick()
+	if n, _ = s.GetNotification(n.ID); n.Emailed {
+		t.Fatal("expected a worker without the lease to skip the cycle")
+	}
+	s.ReleaseLease("worker", "other-instance")
+	worker.NewWorker(s).Tick()
+	if n, _ = s.GetNotification(n.ID); !n.Emailed {
+		t.Fatal("expected the worker to run once the lease is free")
+	}
+
+	// Concurrent creates of the same reminder make one notification.
+	var created atomic.Int64
+	done := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			_, ok, err := s.CreateNotificationOnce(models.Notification{UserID: "lease-user", Message: "Event 'Lab' starts soon.",
+				Type: "EVENT_STARTING", ReferenceID: "event-1", DedupKey: "EVENT_STARTING:event-1:0"})
+			if err == nil && ok {
+				created.Add(1)
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+	if created.Load() != 1 {
+		t.Fatalf("expected one notification to be created, created %d", created.Load())
+	}
+}
+
+func TestCronTick(t *testing.T) {
+	t.Setenv("CRON_SECRET", "cron-secret")
+	s := store.NewInMemoryStore()
+	r := server.SetupRouter(s)
+	tick := func(token string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, "/api/cron/tick", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		var summary map[string]any
+		json.Unmarshal(rr.Body.Bytes(), &summary)
+		return rr, summary
+	}
+
+	for _, token := range []string{"", "wrong-secret"} {
+		if rr, _ := tick(token); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected %q to be refused, got %d", token, rr.Code)
+		}
+	}
+
+	s.CreateUser(models.User{ID: "cron-user", Email: "cron@example.com", IsVerified: true,
+		Preferences: &models.Preferences{Channels: []string{models.ChannelEmail}}})
+	n := s.CreateNotification(models.Notification{UserID: "cron-user", Message: "Task 'Essay' is due soon.", Type: "TASK_DUE"})
+
+	// A cycle running on another instance makes the tick a no-op.
+	s.AcquireLease("worker", "other-instance", time.Minute)
+	if rr, summary := tick("cron-secret"); rr.Code != http.StatusOK || summary["skipped"] != true {
+		t.Fatalf("expected the tick to be skipped, got %d %v", rr.Code, summary)
+	}
+	s.ReleaseLease("worker", "other-instance")
+
+	// Overlapping ticks are safe; the scans and the email run once.
+	codes := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			rr, _ := tick("cron-secret")
+			codes <- rr.Code
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Fatalf("expected concurrent ticks to succeed, got %d", code)
+		}
+	}
+	if n, _ = s.GetNotification(n.ID); !n.Emailed {
+		t.Fatal("expected the tick to email the notification")
+	}
+	rr, summary := tick("cron-secret")
+	if rr.Code != http.StatusOK || summary["skipped"] != false || summary["outOfTime"] != false {
+		t.Fatalf("unexpected tick summary: %d %v", rr.Code, summary)
+	}
+	if _, ok := summary["jobsRun"].(float64); !ok {
+		t.Fatalf("expected the summary to count jobs run, got %v", summary)
+	}
+
+	// A job's I/O ends with the budget, however long its own timeout.
+	hang := make(chan struct{})
+	stub := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-hang:
+		case <-r.Context().Done():
+		}
+	}))
+	defer stub.Close()
+	defer close(hang)
+	h, _ := s.CreateWebhook(models.Webhook{UserID: "cron-user", URL: stub.URL, Events: []string{models.WebhookTaskCreated}, Active: true})
+	s.EnqueueJob(models.Job{Kind: webhook.JobDeliver, Payload: map[string]string{"webhookId": h.ID, "eventId": "evt", "event": "ping", "body": "{}"}})
+	w := worker.NewWorker(s)
+	w.Webhooks = &webhook.Sender{Client: stub.Client()}
+	start := time.Now()
+	cycle, err := w.RunCycle(200 * time.Millisecond)
+	if err != nil || !cycle.OutOfTime || time.Since(start) > 2*time.Second {
+		t.Fatalf("expected the cycle to stop at its budget, got %+v %v after %s", cycle, err, time.Since(start))
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	s := store.NewInMemoryStore()
+	server.SeedStore(s)
+	router := server.SetupRouter(s)
+	slowStarted := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(slowStarted)
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte("done"))
+			return
+		}
+		router.ServeHTTP(w, r)
+	})
+	app := &server.App{
+		Server:          &http.Server{Handler: handler},
+		Worker:          worker.NewWorker(s),
+		Store:           s,
+		ShutdownTimeout: 5 * time.Second,
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- app.Serve(ctx, ln) }()
+
+	// An open subscription is closed by the shutdown.
+	token, _ := auth.GenerateAccessToken("test-user-id")
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws://"+ln.Addr().String()+"/query", nil)
+	if err != nil {
+		t.Fatalf("failed to dial websocket: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	conn.WriteJSON(map[string]any{"type": "connection_init", "payload": map[string]any{"Authorization": "Bearer " + token}})
+	conn.WriteJSON(map[string]any{"id": "1", "type": "subscribe", "payload": map[string]any{
+		"query": `subscription { taskChanged { action } }`,
+	}})
+
+	// An in-flight request is drained rather than cut off.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	slow := make(chan string, 1)
+	go func() {
+		resp, err := client.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		slow <- string(body)
+	}()
+	<-slowStarted
+	cancel()
+
+	if body := <-slow; body != "done" {
+		t.Fatalf("expected the in-flight request to complete, got %q", body)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the app to shut down")
+	}
+	for {
+		var msg map[string]any
+		if err := conn.ReadJSON(&msg); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				t.Fatal("expected the subscription to be closed")
+			}
+			break
+		}
+	}
+	conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > baseline {
+		buf := make([]byte, 1<<16)
+		t.Fatalf("expected no goroutines to leak, %d running, %d before:\n%s", n, baseline, buf[:runtime.Stack(buf, true)])
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	server.Now = fake.Now
+	defer func() { server.Now = nil }()
+	s := store.NewInMemoryStore()
+	s.Now = fake.Now
+	r := server.SetupRouter(s)
+
+	// Tokens expire by the server's clock.
+	access, _ := auth.GenerateAccessToken("clock-user")
+	refresh, _ := auth.GenerateRefreshToken("clock-user")
+	fake.Advance(23 * time.Hour)
+	if _, err := auth.ValidateToken(access); err != nil {
+		t.Fatalf("expected the access token to be valid for a day, got %v", err)
+	}
+	fake.Advance(2 * time.Hour)
+	if _, err := auth.ValidateToken(access); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Fatalf("expected the access token to have expired, got %v", err)
+	}
+	if _, err := auth.ValidateToken(refresh); err != nil {
+		t.Fatalf("expected the refresh token to outlive the access token, got %v", err)
+	}
+	fake.Advance(30 * 24 * time.Hour)
+	if _, err := auth.ValidateToken(refresh); err == nil {
+		t.Fatal("expected the refresh token to have expired")
+	}
+
+	// A day of worker ticks sends each reminder on time, and emails it once
+	// it has gone unread for the user's email delay, outside quiet hours.
+	fake.Set(start)
+	w := worker.NewWorker(s)
+	w.Now = fake.Now
+	if !w.Webhooks.Now().Equal(start) {
+		t.Fatal("expected the worker's webhooks to be signed by its clock")
+	}
+	prefs := models.DefaultPreferences()
+	prefs.QuietHours = &models.QuietHours{Start: 21 * 60, End: 7 * 60}
+	s.CreateUser(models.User{ID: "clock-user", Email: "clock@example.com", IsVerified: true, Preferences: &prefs})
+	s.CreateTask(models.Task{ID: "clock-task", Title: "Essay", CourseID: "course-1", UserID: "clock-user",
+		DueAt: start.Add(18 * time.Hour), HasReminder: true, Reminders: []models.Reminder{{Before: 2 * time.Hour}}})
+	s.CreateTask(models.Task{ID: "late-task", Title: "Quiz", CourseID: "course-1", UserID: "clock-user",
+		DueAt: start.Add(23 * time.Hour), HasReminder: true, Reminders: []models.Reminder{{Before: time.Hour}}})
+	s.CreateEvent(models.Event{ID: "clock-event", Title: "Lab", UserID: "clock-user",
+		StartsAt: start.Add(33 * time.Hour), EndsAt: start.Add(35 * time.Hour)})
+
+	created := map[string]time.Time{}
+	emailed := map[string]time.Time{}
+	for i := 0; i < 24*60; i++ {
+		fake.Advance(time.Minute)
+		w.Tick()
+		for _, n := range s.GetNotifications("clock-user", false) {
+			if _, ok := created[n.ReferenceID]; !ok {
+				created[n.ReferenceID] = fake.Now()
+			}
+			if _, ok := emailed[n.ReferenceID]; !ok && n.Emailed {
+				emailed[n.ReferenceID] = fake.Now()
+			}
+		}
+	}
+	want := map[string][2]time.Duration{
+		"clock-task":  {16 * time.Hour, 17 * time.Hour},
+		"clock-event": {9 * time.Hour, 10 * time.Hour},
+		// Due to be emailed at 23:00, which is in quiet hours until 07:00
+		"late-task": {22 * time.Hour, 0},
+	}
+	for ref, at := range want {
+		if !created[ref].Equal(start.Add(at[0])) {
+			t.Errorf("expected %s to be notified at %s, was at %s", ref, start.Add(at[0]), created[ref])
+		}
+		if at[1] == 0 {
+			if !emailed[ref].IsZero() {
+				t.Errorf("expected %s not to be emailed yet, was at %s", ref, emailed[ref])
+			}
+		} else if !emailed[ref].Equal(start.Add(at[1])) {
+			t.Errorf("expected %s to be emailed at %s, was at %s", ref, start.Add(at[1]), emailed[ref])
+		}
+	}
+	if n := len(s.GetNotifications("clock-user", false)); n != 3 {
+		t.Fatalf("expected one notification per reminder, got %d", n)
+	}
+
+	// The server's clock stamps completions and snoozes.
+	token, _ := auth.GenerateAccessToken("clock-user")
+	resp := graphQL(t, r, token, `mutation{ updateTask(input: {id: "clock-task", completed: true}){ completedAt } }`, nil)
+	if got := resp["data"].(map[string]any)["updateTask"].(map[string]any)["completedAt"]; got != fake.Now().Format(time.RFC3339) {
+		t.Fatalf("expected the task to be completed at %s, got %v", fake.Now(), resp)
+	}
+	id := s.GetNotifications("clock-user", false)[0].ID
+	graphQL(t, r, token, `mutation($id: ID!){ snoozeNotification(id: $id, minutes: 10){ id } }`, map[string]any{"id": id})
+	if n, _ := s.GetNotification(id); n.SnoozedUntil == nil || !n.SnoozedUntil.Equal(fake.Now().Add(10*time.Minute)) {
+		t.Fatalf("expected the notification to be snoozed for 10 minutes from %s, got %v", fake.Now(), n.SnoozedUntil)
+	}
+}
+
+func TestEmailTemplates(t *testing.T) {
+	t.Setenv("BASE_URL", "https://studybuddy.example.com")
+	tmpl, err := email.LoadTemplates("")
+	if err != nil {
+		t.Fatalf("failed to load the built-in templates: %v", err)
+	}
+	msg, err := tmpl.Render(email.TemplateVerification, "student@example.com", email.VerificationData{Link: "https://studybuddy.example.com/verify-email?token=abc&x=1"})
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	raw, _ := msg.Bytes("StudyBuddy <noreply@studybuddy.example.com>", time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	for k, want := range map[string]string{
+		"From":         "StudyBuddy <noreply@studybuddy.example.com>",
+		"To":           "student@example.com",
+		"Subject":      "Verify your email",
+		"Date":         "Mon, 02 Mar 2026 09:00:00 +0000",
+		"MIME-Version": "1.0",
+	} {
+		if got := parsed.Header.Get(k); got != want {
+			t.Errorf("expected %s %q, got %q", k, want, got)
+		}
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasSuffix(id, "@studybuddy.example.com>") {
+		t.Errorf("unexpected Message-ID %q", id)
+	}
+	mediaType, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("expected a multipart/alternative message, got %q", mediaType)
+	}
+	parts := map[string]string{}
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		body, _ := io.ReadAll(p)
+		partType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[partType] = string(body)
+	}
+	if text := parts["text/plain"]; !strings.Contains(text, "https://studybuddy.example.com/verify-email?token=abc&x=1") || !strings.Contains(text, "StudyBuddy") {
+		t.Fatalf("unexpected text part: %q", text)
+	}
+	if html := parts["text/html"]; !strings.Contains(html, `href="https://studybuddy.example.com/verify-email?token=abc&amp;x=1"`) {
+		t.Fatalf("unexpected HTML part: %q", html)
+	}
+
+	// Data is escaped in the HTML body and digests list their sections.
+	msg, _ = tmpl.Render(email.TemplateReminder, "student@example.com", email.ReminderData{Subject: "Task due", Message: "Task '<b>Essay</b>' is due soon."})
+	if !strings.Contains(msg.HTML, "&lt;b&gt;Essay&lt;/b&gt;") || !strings.Contains(msg.Text, "<b>Essay</b>") {
+		t.Fatalf("unexpected reminder bodies: %q %q", msg.Text, msg.HTML)
+	}
+	msg, _ = tmpl.Render(email.TemplateDigest, "student@example.com", email.DigestData{Title: "Your week ahead",
+		Sections: []email.DigestSection{{Heading: "Due this week", Items: []string{"Essay", "Lab report"}}}})
+	if msg.Subject != "Your week ahead" || !strings.Contains(msg.Text, "  - Lab report") || !strings.Contains(msg.HTML, "<li style=\"margin:4px 0;\">Essay</li>") {
+		t.Fatalf("unexpected digest: %+v", msg)
+	}
+
+	// Files in the template directory replace the built-in ones.
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "reminder.txt"), []byte(`{{define "subject"}}Heads up: {{.Data.Subject}}{{end}}{{define "content"}}{{.Data.Message}}{{end}}`), 0o644)
+	tmpl, err = email.LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("failed to load overridden templates: %v", err)
+	}
+	msg, _ = tmpl.Render(email.TemplateReminder, "student@example.com", email.ReminderData{Subject: "Task due", Message: "Essay"})
+	if msg.Subject != "Heads up: Task due" || !strings.Contains(msg.HTML, "<html>") {
+		t.Fatalf("expected the text template to be overridden and the HTML one kept, got %+v", msg)
+	}
+	os.WriteFile(filepath.Join(dir, "digest.html"), []byte(`{{define "content"}}{{.Data.Title}`), 0o644)
+	if _, err := email.LoadTemplates(dir); err == nil {
+		t.Fatal("expected a broken template to fail to load")
+	}
+}
+
+func TestMailers(t *testing.T) {
+	t.Setenv("BASE_URL", "https://studybuddy.example.com")
+
+	// The server and worker send through the injected mailer.
+	mailer := &email.MemoryMailer{}
+	server.Mailer = mailer
+	t.Cleanup(func() { server.Mailer = nil })
+	s := store.NewInMemoryStore()
+	r := server.SetupRouter(s)
+	graphQL(t, r, "", `mutation{ register(input: {name: "Mia", email: "mia@example.com", password: "correct horse battery"}){ token } }`, nil)
+	for i := 0; i < 100 && len(mailer.Sent()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	sent := mailer.Sent()
+	if len(sent) != 1 || sent[0].To != "mia@example.com" || sent[0].Subject != "Verify your email" ||
+		!strings.Contains(sent[0].Text, "https://studybuddy.example.com/verify-email?token=") {
+		t.Fatalf("expected a verification email, got %+v", sent)
+	}
+
+	s.CreateUser(models.User{ID: "mail-user", Email: "mail@example.com", IsVerified: true,
+		Preferences: &models.Preferences{Channels: []string{models.ChannelEmail}}})
+	s.CreateNotification(models.Notification{UserID: "mail-user", Message: "Task 'Essay' is due soon.", Type: "TASK_DUE"})
+	w := worker.NewWorker(s)
+	w.Mailer = mailer
+	w.Tick()
+	if sent = mailer.Sent(); len(sent) != 2 || sent[1].To != "mail@example.com" || !strings.Contains(sent[1].Text, "Task 'Essay' is due soon.") {
+		t.Fatalf("expected the worker to email the notification, got %+v", sent)
+	}
+
+	// The file sink writes each message as an .eml file.
+	dir := t.TempDir()
+	t.Setenv("EMAIL_OUTBOX_DIR", dir)
+	m, err := email.MailerFromEnv()
+	if err != nil {
+		t.Fatalf("failed to create mailer: %v", err)
+	}
+	if err := m.Send(context.Background(), sent[1]); err != nil {
+		t.Fatalf("failed to write email: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected one .eml file, got %v", files)
+	}
+	f, _ := os.Open(files[0])
+	defer f.Close()
+	if parsed, err := mail.ReadMessage(f); err != nil || parsed.Header.Get("To") != "mail@example.com" {
+		t.Fatalf("expected a readable message, got %v", err)
+	}
+
+	// The SMTP mailer authenticates and delivers to the server.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go serveSMTPStub(ln, received)
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	t.Setenv("EMAIL_OUTBOX_DIR", "")
+	t.Setenv("SMTP_HOST", "127.0.0.1")
+	t.Setenv("SMTP_PORT", port)
+	t.Setenv("SMTP_USER", "smtp-user")
+	t.Setenv("SMTP_PASS", "smtp-pass")
+	t.Setenv("EMAIL_FROM", "StudyBuddy <noreply@studybuddy.example.com>")
+	if m, err = email.MailerFromEnv(); err != nil {
+		t.Fatalf("failed to create mailer: %v", err)
+	}
+	if smtpMailer, ok := m.(*email.SMTPMailer); !ok || smtpMailer.ImplicitTLS {
+		t.Fatalf("expected a STARTTLS SMTP mailer, got %#v", m)
+	}
+	if err := m.Send(context.Background(), sent[1]); err != nil {
+		t.Fatalf("failed to send over SMTP: %v", err)
+	}
+	transcript := <-received
+	for _, want := range []string{"AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00smtp-user\x00smtp-pass")),
+		"MAIL FROM:<noreply@studybuddy.example.com>", "RCPT TO:<mail@example.com>", "Content-Type: multipart/alternative"} {
+		if !strings.Contains(transcript, want) {
+			t.Fatalf("expected the SMTP session to contain %q, got:\n%s", want, transcript)
+		}
+	}
+
+	t.Setenv("SMTP_TLS", "ssl")
+	if _, err := email.MailerFromEnv(); err == nil {
+		t.Fatal("expected an invalid SMTP_TLS to be rejected")
+	}
+}
+
+// serveSMTPStub accepts one SMTP session on ln, without STARTTLS, and sends
+// what the client wrote to received.
+func serveSMTPStub(ln net.Listener, received chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	var transcript strings.Builder
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	reply := func(line string) {
+		rw.WriteString(line + "\r\n")
+		rw.Flush()
+	}
+	reply("220 stub ESMTP")
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			break
+		}
+		transcript.WriteString(line)
+		switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+		case "EHLO":
+			reply("250-stub")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 authenticated")
+		case "DATA":
+			reply("354 go ahead")
+			for {
+				line, err := rw.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				transcript.WriteString(line)
+			}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			received <- transcript.String()
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+	received <- transcript.String()
+}
+
+func TestDigests(t *testing.T) {
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	weekly := models.Digest{Frequency: models.DigestWeekly, At: 7 * 60}
+	if due := weekly.LastDue(time.Date(2026, 3, 4, 12, 0, 0, 0, colombo), colombo, time.Monday); !due.Equal(time.Date(2026, 3, 2, 7, 0, 0, 0, colombo)) {
+		t.Fatalf("expected the weekly digest to be due on Monday, got %s", due)
+	}
+	if due := weekly.LastDue(time.Date(2026, 3, 2, 6, 0, 0, 0, colombo), colombo, time.Monday); !due.Equal(time.Date(2026, 2, 23, 7, 0, 0, 0, colombo)) {
+		t.Fatalf("expected the weekly digest to be due the Monday before, got %s", due)
+	}
+
+	s := store.NewInMemoryStore()
+	r := server.SetupRouter(s)
+	s.CreateUser(models.User{ID: "digest-user", Email: "digest@example.com", IsVerified: true, TimeZone: "Asia/Colombo"})
+	token, _ := auth.GenerateAccessToken("digest-user")
+	resp := graphQL(t, r, token, `mutation{ updatePreferences(input: {digest: {frequency: DAILY, at: "07:00"}}){ digest{ frequency at } } }`, nil)
+	if d := resp["data"].(map[string]any)["updatePreferences"].(map[string]any)["digest"].(map[string]any); d["frequency"] != "DAILY" || d["at"] != "07:00" {
+		t.Fatalf("unexpected digest preferences: %v", resp)
+	}
+
+	// A day's digest batches the user's tasks, events and unread
+	// notifications into one email at 07:00 local time.
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, colombo)
+	fake := clock.NewFake(start)
+	s.Now = fake.Now
+	mailer := &email.MemoryMailer{}
+	w := worker.NewWorker(s)
+	w.Now, w.Mailer = fake.Now, mailer
+	s.CreateTask(models.Task{ID: "digest-overdue", Title: "Lab report", UserID: "digest-user", DueAt: start.Add(-48 * time.Hour)})
+	s.CreateTask(models.Task{ID: "digest-today", Title: "Essay", UserID: "digest-user", DueAt: start.Add(18 * time.Hour)})
+	s.CreateTask(models.Task{ID: "digest-later", Title: "Thesis", UserID: "digest-user", DueAt: start.Add(5 * 24 * time.Hour)})
+	s.CreateTask(models.Task{ID: "digest-done", Title: "Quiz", UserID: "digest-user", DueAt: start.Add(-time.Hour), Completed: true})
+	s.CreateEvent(models.Event{ID: "digest-event", Title: "Seminar", UserID: "digest-user", StartsAt: start.Add(15 * time.Hour), EndsAt: start.Add(16 * time.Hour)})
+	s.CreateNotification(models.Notification{UserID: "digest-user", Message: "Course 'CS50' was updated.", Type: "INFO"})
+
+	for i := 0; i < 8*60; i++ {
+		fake.Advance(time.Minute)
+		w.Tick()
+		if n := len(mailer.Sent()); n > 0 && fake.Now().Before(start.Add(7*time.Hour)) {
+			t.Fatalf("expected nothing to be emailed before the digest, got %+v", mailer.Sent())
+		}
+	}
+	sent := mailer.Sent()
+	if len(sent) != 1 || sent[0].Subject != "Your day ahead" {
+		t.Fatalf("expected one digest email, got %+v", sent)
+	}
+	for _, want := range []string{"Overdue", "Lab report (was due Sat 28 Feb 00:00)", "Essay (due Mon 2 Mar 18:00)", "Seminar (Mon 2 Mar 15:00)",
+		"Course 'CS50' was updated.", "Event 'Seminar'"} {
+		if !strings.Contains(sent[0].Text, want) {
+			t.Errorf("expected the digest to contain %q:\n%s", want, sent[0].Text)
+		}
+	}
+	if strings.Contains(sent[0].Text, "Thesis") || strings.Contains(sent[0].Text, "Quiz") {
+		t.Errorf("expected later and completed tasks to be left out:\n%s", sent[0].Text)
+	}
+
+	// The next digest is sent the next morning.
+	fake.Set(start.Add(31 * time.Hour))
+	w.Tick()
+	if sent = mailer.Sent(); len(sent) != 2 {
+		t.Fatalf("expected a second digest the next day, got %d emails", len(sent))
+	}
+}
+
+// countingStore counts the store round trips made while serving a request.
+type countingStore struct {
+	store.Store
+	calls atomic.Int64
+}
+
+func (c *countingStore) GetTasks(userID string) []models.Task {
+	c.calls.Add(1)
+	return c.Store.GetTasks(userID)
+}
+
+func (c *countingStore) GetCourses(userID string) []models.Course {
+	c.calls.Add(1)
+	return c.Store.GetCourses(userID)
+}
+
+func (c *countingStore) GetCourse(id string) (models.Course, error) {
+	c.calls.Add(1)
+	return c.Store.GetCourse(id)
+}
+
+func (c *countingStore) GetCoursesByIDs(ids []string) ([]models.Course, error) {
+	c.calls.Add(1)
+	return c.Store.GetCoursesByIDs(ids)
+}
+
+func (c *countingStore) CountTasksByCourse(courseIDs []string) (map[string]store.TaskCounts, error) {
+	c.calls.Add(1)
+	return c.Store.CountTasksByCourse(courseIDs)
+}
+
+func (c *countingStore) ListEvents(userID string, f store.EventFilter) ([]models.Event, error) {
+	c.calls.Add(1)
+	return c.Store.ListEvents(userID, f)
+}
+
+// BenchmarkScreenStoreCalls reports the store round trips needed by the
+// app's main screens for a user with 200 tasks, 50 events and 5 courses.
+func BenchmarkScreenStoreCalls(b *testing.B) {
+	inner := store.NewInMemoryStore()
+	userID := "bench-user-id"
+	for i := 0; i < 5; i++ {
+		inner.CreateCourse(models.Course{ID: fmt.Sprintf("bench-course-%d", i), Name: "Course", Color: "#000000", UserID: userID})
+	}
+	for i := 0; i < 200; i++ {
+		inner.CreateTask(models.Task{
+			ID:        fmt.Sprintf("bench-task-%d", i),
+			Title:     "Task",
+			CourseID:  fmt.Sprintf("bench-course-%d", i%5),
+			UserID:    userID,
+			DueAt:     time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC),
+			Completed: i%3 == 0,
+		})
+	}
+	for i := 0; i < 50; i++ {
+		inner.CreateEvent(models.Event{
+			ID:       fmt.Sprintf("bench-event-%d", i),
+			Title:    "Event",
+			CourseID: fmt.Sprintf("bench-course-%d", i%5),
+			UserID:   userID,
+			StartsAt: time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC),
+			EndsAt:   time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC),
+			Type:     "CLASS",
+		})
+	}
+	token, _ := auth.GenerateAccessToken(userID)
+	b.Setenv("RATE_LIMIT_USER_PER_MINUTE", "0")
+	b.Setenv("RATE_LIMIT_IP_PER_MINUTE", "0")
+
+	screens := []struct {
+		name  string
+		query string
+	}{
+		{"TaskList", `{ tasks { id title course { name color } } }`},
+		{"Courses", `{ courses { id name totalTasks completedTasks } }`},
+		{"Calendar", `{ events { id title course { name color } } }`},
+	}
+	for _, screen := range screens {
+		b.Run(screen.name, func(b *testing.B) {
+			s := &countingStore{Store: inner}
+			r := server.SetupRouter(s)
+			body, _ := json.Marshal(map[string]string{"query": screen.query})
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+token)
+				rr := httptest.NewRecorder()
+				r.ServeHTTP(rr, req)
+				if strings.Contains(rr.Body.String(), `"errors"`) {
+					b.Fatalf("query failed: %s", rr.Body.String())
+				}
+			}
+			b.ReportMetric(float64(s.calls.Load())/float64(b.N), "storecalls/op")
+		})
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	t.Setenv("BASE_URL", "https://studybuddy.example.com")
+	t.Setenv("ADMIN_EMAILS", "ops@example.com")
+	s := store.NewInMemoryStore()
+	r := server.SetupRouter(s)
+	mailer := &email.MemoryMailer{}
+	w := worker.NewWorker(s)
+	w.Mailer = email.WithSuppression(mailer, s)
+
+	s.CreateUser(models.User{ID: "unsub-user", Email: "Unsub@example.com", IsVerified: true,
+		Preferences: &models.Preferences{Channels: []string{models.ChannelEmail}}})
+	s.CreateNotification(models.Notification{UserID: "unsub-user", Message: "Task 'Essay' is due soon.", Type: "TASK_DUE"})
+	w.Tick()
+	sent := mailer.Sent()
+	if len(sent) != 1 || sent[0].Category != email.CategoryNotifications ||
+		!strings.HasPrefix(sent[0].Unsubscribe, "https://studybuddy.example.com/unsubscribe?token=") ||
+		!strings.Contains(sent[0].Text, sent[0].Unsubscribe) {
+		t.Fatalf("expected a notification email with an unsubscribe link, got %+v", sent)
+	}
+	raw, _ := sent[0].Bytes("noreply@example.com", time.Now())
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil || parsed.Header.Get("List-Unsubscribe") != "<"+sent[0].Unsubscribe+">" ||
+		parsed.Header.Get("List-Unsubscribe-Post") != "List-Unsubscribe=One-Click" {
+		t.Fatalf("expected one-click unsubscribe headers, got %v %v", parsed.Header, err)
+	}
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	link := strings.TrimPrefix(sent[0].Unsubscribe, "https://studybuddy.example.com")
+	if rr := do(http.MethodGet, link+"x", ""); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected a tampered link to be rejected, got %d", rr.Code)
+	}
+	// Opening the link only asks for confirmation, so link scanners do not
+	// unsubscribe anyone.
+	if rr := do(http.MethodGet, link, ""); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Unsub@example.com") {
+		t.Fatalf("expected a confirmation page, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ok, _ := s.IsSuppressed("unsub@example.com", email.CategoryNotifications); ok {
+		t.Fatal("expected GET not to unsubscribe")
+	}
+	if rr := do(http.MethodPost, link, "List-Unsubscribe=One-Click"); rr.Code != http.StatusOK {
+		t.Fatalf("expected one-click unsubscribe to succeed, got %d", rr.Code)
+	}
+	if ok, _ := s.IsSuppressed("unsub@example.com", email.CategoryNotifications); !ok {
+		t.Fatal("expected the address to be unsubscribed from notifications")
+	}
+	if ok, _ := s.IsSuppressed("unsub@example.com", email.CategoryDigest); ok {
+		t.Fatal("expected the digest to be unaffected")
+	}
+
+	// Later notifications are not emailed, but still marked as handled.
+	n := s.CreateNotification(models.Notification{UserID: "unsub-user", Message: "Task 'Lab' is due soon.", Type: "TASK_DUE"})
+	w.Tick()
+	if n, _ = s.GetNotification(n.ID); len(mailer.Sent()) != 1 || !n.Emailed {
+		t.Fatalf("expected the notification not to be emailed, got %+v", mailer.Sent())
+	}
+
+	if rr := do(http.MethodPost, link, "action=resubscribe"); rr.Code != http.StatusOK {
+		t.Fatalf("expected resubscribing to succeed, got %d", rr.Code)
+	}
+	if ok, _ := s.IsSuppressed("unsub@example.com", email.CategoryNotifications); ok {
+		t.Fatal("expected the address to be resubscribed")
+	}
+
+	// A bounced address gets no email at all, not even transactional email.
+	s.CreateUser(models.User{ID: "ops-user", Email: "ops@example.com", IsVerified: true})
+	admin, _ := auth.GenerateAccessToken("ops-user")
+	resp := graphQL(t, r, admin, `mutation{ suppressEmail(email: "bounce@example.com", reason: BOUNCED){ email category reason } }`, nil)
+	if sup := resp["data"].(map[string]any)["suppressEmail"].(map[string]any); sup["category"] != nil || sup["reason"] != "BOUNCED" {
+		t.Fatalf("unexpected suppression: %v", resp)
+	}
+	if err := email.SendVerificationEmail(context.Background(), w.Mailer, "bounce@example.com", "en", "token"); err != nil || len(mailer.Sent()) != 1 {
+		t.Fatalf("expected the verification email to be dropped, got %v %+v", err, mailer.Sent())
+	}
+	resp = graphQL(t, r, admin, `{ emailSuppressions(email: "bounce@example.com"){ email } }`, nil)
+	if list := resp["data"].(map[string]any)["emailSuppressions"].([]any); len(list) != 1 {
+		t.Fatalf("unexpected suppressions: %v", resp)
+	}
+	resp = graphQL(t, r, admin, `mutation{ unsuppressEmail(email: "bounce@example.com") }`, nil)
+	if resp["data"].(map[string]any)["unsuppressEmail"] != true {
+		t.Fatalf("expected the suppression to be lifted, got %v", resp)
+	}
+	if err := email.SendVerificationEmail(context.Background(), w.Mailer, "bounce@example.com", "en", "token"); err != nil || len(mailer.Sent()) != 2 {
+		t.Fatalf("expected the verification email to be sent, got %v %+v", err, mailer.Sent())
+	}
+
+	// In production the public development key signs nothing, so links
+	// cannot be forged with it.
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("UNSUBSCRIBE_SECRET", "")
+	t.Setenv("JWT_SECRET", "dev-secret")
+	if url := email.UnsubscribeURL("unsub@example.com", email.CategoryNotifications); url != "" {
+		t.Fatalf("expected no unsubscribe link without a secret, got %q", url)
+	}
+	if rr := do(http.MethodPost, link, "List-Unsubscribe=One-Click"); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected unsubscribing to be disabled, got %d", rr.Code)
+	}
+	t.Setenv("UNSUBSCRIBE_SECRET", "unsubscribe-secret")
+	if rr := do(http.MethodPost, link, "List-Unsubscribe=One-Click"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected a link signed with the development key to be rejected, got %d", rr.Code)
+	}
+	if url := email.UnsubscribeURL("unsub@example.com", email.CategoryNotifications); url == "" {
+		t.Fatal("expected an unsubscribe link once a secret is set")
+	}
+}
+
+func TestWebPush(t *testing.T) {
+	// RFC 8291 Appendix A: the user agent decrypts the example message.
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	uaKey, _ := ecdh.P256().NewPrivateKey(decode("q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"))
+	plain, err := push.Decrypt(uaKey, decode("BTBZMqHH6r4Tts7J_aSIgg"), decode("DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"))
+	if err != nil || string(plain) != "When I grow up, I want to be a watermelon" {
+		t.Fatalf("expected the RFC 8291 example to decrypt, got %q %v", plain, err)
+	}
+
+	private, _ := push.GenerateVAPIDKey()
+	vapidKey, err := push.ParseVAPIDKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := &push.WebPush{VAPID: &push.VAPID{PrivateKey: vapidKey, Subject: "mailto:ops@example.com"}}
+
+	// A browser's subscription keys, held by the push service stub.
+	browserKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+	authSecret := make([]byte, 16)
+	rand.Read(authSecret)
+	var received atomic.Value
+	stub := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/push/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		var token, key string
+		if _, err := fmt.Sscanf(strings.ReplaceAll(r.Header.Get("Authorization"), ",", ""), "vapid t=%s k=%s", &token, &key); err != nil ||
+			key != sender.VAPID.PublicKey() || r.Header.Get("Content-Encoding") != "aes128gcm" {
+			http.Error(w, "bad headers", http.StatusBadRequest)
+			return
+		}
+		claims := jwt.RegisteredClaims{}
+		if _, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) { return &vapidKey.PublicKey, nil }); err != nil ||
+			!claims.VerifyAudience("https://"+r.Host, true) || claims.Subject != "mailto:ops@example.com" {
+			http.Error(w, "bad VAPID token", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		plain, err := push.Decrypt(browserKey, authSecret, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received.Store(plain)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer stub.Close()
+	sender.Client = stub.Client()
+
+	server.WebPush = sender
+	t.Cleanup(func() { server.WebPush = nil })
+	s := store.NewInMemoryStore()
+	r := server.SetupRouter(s)
+	prefs := models.DefaultPreferences()
+	prefs.Channels = []string{models.ChannelInApp, models.ChannelPush}
+	s.CreateUser(models.User{ID: "push-user", Email: "push@example.com", IsVerified: true, Preferences: &prefs})
+	token, _ := auth.GenerateAccessToken("push-user")
+
+	resp := graphQL(t, r, token, `{ vapidPublicKey }`, nil)
+	if resp["data"].(map[string]any)["vapidPublicKey"] != sender.VAPID.PublicKey() {
+		t.Fatalf("unexpected VAPID public key: %v", resp)
+	}
+	register := `mutation($input: PushSubscriptionInput!){ registerPushSubscription(input: $input){ id endpoint device } }`
+	subscription := func(endpoint string) map[string]any {
+		return map[string]any{"input": map[string]any{
+			"endpoint": endpoint,
+			"p256dh":   base64.RawURLEncoding.EncodeToString(browserKey.PublicKey().Bytes()),
+			"auth":     base64.RawURLEncoding.EncodeToString(authSecret),
+			"device":   "Firefox on Linux",
+		}}
+	}
+	for _, bad := range []map[string]any{
+		subscription("http://push.example.com/push/ok"),
+		{"input": map[string]any{"endpoint": stub.URL + "/push/ok", "p256dh": "not-a-key", "auth": "BTBZMqHH6r4Tts7J_aSIgg"}},
+	} {
+		if resp := graphQL(t, r, token, register, bad); resp["errors"] == nil {
+			t.Fatalf("expected an invalid subscription to be rejected: %v", bad)
+		}
+	}
+	if resp := graphQL(t, r, token, register, subscription(stub.URL+"/push/ok")); resp["errors"] == nil {
+		t.Fatalf("expected an endpoint on a loopback address to be rejected: %v", resp)
+	}
+	t.Setenv("ALLOW_PRIVATE_HOSTS", "true")
+	resp = graphQL(t, r, token, register, subscription(stub.URL+"/push/ok"))
+	first := resp["data"].(map[string]any)["registerPushSubscription"].(map[string]any)
+	// Subscribing again from the same browser updates its subscription.
+	resp = graphQL(t, r, token, register, subscription(stub.URL+"/push/ok"))
+	if again := resp["data"].(map[string]any)["registerPushSubscription"].(map[string]any); again["id"] != first["id"] {
+		t.Fatalf("expected the subscription to be updated, got %v and %v", first, again)
+	}
+	graphQL(t, r, token, register, subscription(stub.URL+"/push/gone"))
+
+	// A reminder is pushed to each device, and the expired one is pruned.
+	w := worker.NewWorker(s)
+	w.WebPush = sender
+	s.CreateTask(models.Task{ID: "push-task", Title: "Quiz", UserID: "push-user", DueAt: time.Now().Add(2 * time.Hour), HasReminder: true})
+	w.Tick()
+	plain, _ = received.Load().([]byte)
+	var payload worker.PushPayload
+	if err := json.Unmarshal(plain, &payload); err != nil || payload.Type != "TASK_DUE" || payload.ReferenceID != "push-task" ||
+		!strings.HasPrefix(payload.Body, "Task 'Quiz' is due") {
+		t.Fatalf("unexpected push payload %q: %v", plain, err)
+	}
+	resp = graphQL(t, r, token, `{ pushSubscriptions{ id endpoint } }`, nil)
+	if subs := resp["data"].(map[string]any)["pushSubscriptions"].([]any); len(subs) != 1 || subs[0].(map[string]any)["id"] != first["id"] {
+		t.Fatalf("expected the gone subscription to be pruned, got %v", resp)
+	}
+
+	other, _ := auth.GenerateAccessToken("someone-else")
+	s.CreateUser(models.User{ID: "someone-else", Email: "else@example.com", IsVerified: true})
+	del := `mutation($id: ID!){ deletePushSubscription(id: $id) }`
+	if resp := graphQL(t, r, other, del, map[string]any{"id": first["id"]}); resp["errors"] == nil {
+		t.Fatal("expected another user not to delete the subscription")
+	}
+	if resp := graphQL(t, r, token, del, map[string]any{"id": first["id"]}); resp["data"].(map[string]any)["deletePushSubscription"] != true {
+		t.Fatalf("expected the subscription to be deleted, got %v", resp)
+	}
+}
+
+func TestMobilePush(t *testing.T) {
+	// A fake FCM, with its OAuth token endpoint, checking the service
+	// account's signed assertion.
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var tokensIssued atomic.Int64
+	var sent atomic.Value
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			claims := jwt.MapClaims{}
+			_, err := jwt.ParseWithClaims(r.FormValue("assertion"), claims, func(*jwt.Token) (any, error) { return &key.PublicKey, nil })
+			if err != nil || claims["iss"] != "push@studybuddy.iam.gserviceaccount.com" ||
+				r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+				http.Error(w, "invalid assertion", http.StatusBadRequest)
+				return
+			}
+			tokensIssued.Add(1)
+			json.NewEncoder(w).Encode(map[string]any{"access_token": "fake-access-token", "expires_in": 3600})
+			return
+		}
+		if r.URL.Path != "/v1/projects/studybuddy-test/messages:send" || r.Header.Get("Authorization") != "Bearer fake-access-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Message struct {
+				Token        string
+				Notification struct{ Title, Body string }
+				Data         map[string]string
+				Android      struct{ Priority string }
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body.Message.Token {
+		case "stale-token":
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error":{"code":404,"status":"NOT_FOUND","message":"Requested entity was not found.","details":[{"errorCode":"UNREGISTERED"}]}}`)
+		case "bogus-token":
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":{"code":400,"status":"INVALID_ARGUMENT","message":"The registration token is not a valid FCM registration token",`+
+				`"details":[{"@type":"type.googleapis.com/google.rpc.BadRequest","fieldViolations":[{"field":"message.token"}]}]}}`)
+		case "flaky-token":
+			http.Error(w, `{"error":{"code":503,"status":"UNAVAILABLE"}}`, http.StatusServiceUnavailable)
+		default:
+			sent.Store(body.Message)
+			io.WriteString(w, `{"name":"projects/studybuddy-test/messages/1"}`)
+		}
+	}))
+	defer fake.Close()
+
+	credentials := filepath.Join(t.TempDir(), "service-account.json")
+	sa, _ := json.Marshal(push.ServiceAccount{
+		ProjectID:   "studybuddy-test",
+		ClientEmail: "push@studybuddy.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		TokenURI:    fake.URL + "/token",
+	})
+	os.WriteFile(credentials, sa, 0o600)
+	t.Setenv("FCM_CREDENTIALS_FILE", credentials)
+	t.Setenv("FCM_ENDPOINT", fake.URL)
+	provider, err := push.FCMFromEnv()
+	if err != nil || provider == nil {
+		t.Fatalf("expected an FCM provider, got %v", err)
+	}
+
+	s := store.NewInMemoryStore()
+	r := server.SetupRouter(s)
+	prefs := models.DefaultPreferences()
+	prefs.Channels = []string{models.ChannelInApp, models.ChannelPush}
+	s.CreateUser(models.User{ID: "mobile-user", Email: "mobile@example.com", IsVerified: true, Preferences: &prefs})
+	token, _ := auth.GenerateAccessToken("mobile-user")
+
+	register := `mutation($input: DeviceTokenInput!){ registerDeviceToken(input: $input){ id platform device } }`
+	if resp := graphQL(t, r, token, register, map[string]any{"input": map[string]any{"token": " ", "platform": "ANDROID"}}); resp["errors"] == nil {
+		t.Fatal("expected an empty token to be rejected")
+	}
+	ids := map[string]string{}
+	for _, d := range []struct{ token, platform string }{
+		{"good-token", "ANDROID"}, {"stale-token", "IOS"}, {"bogus-token", "IOS"}, {"flaky-token", "ANDROID"}, {"good-token", "ANDROID"},
+	} {
+		resp := graphQL(t, r, token, register, map[string]any{"input": map[string]any{"token": d.token, "platform": d.platform, "device": "Pixel 8"}})
+		device := resp["data"].(map[string]any)["registerDeviceToken"].(map[string]any)
+		if id, ok := ids[d.token]; ok && id != device["id"] {
+			t.Fatalf("expected registering a token again to keep its ID, got %v", resp)
+		}
+		ids[d.token] = device["id"].(string)
+	}
+
+	// A reminder is pushed to each device: the stale and invalid ones are
+	// deleted, and the one whose push failed is kept for a retry.
+	w := worker.NewWorker(s)
+	w.Push = provider
+	s.CreateTask(models.Task{ID: "mobile-task", Title: "Quiz", UserID: "mobile-user", DueAt: time.Now().Add(2 * time.Hour), HasReminder: true})
+	w.Tick()
+	msg, ok := sent.Load().(struct {
+		Token        string
+		Notification struct{ Title, Body string }
+		Data         map[string]string
+		Android      struct{ Priority string }
+	})
+	if !ok || msg.Token != "good-token" || msg.Notification.Title != email.Brand ||
+		!strings.HasPrefix(msg.Notification.Body, "Task 'Quiz' is due") || msg.Data["referenceId"] != "mobile-task" || msg.Android.Priority != "HIGH" {
+		t.Fatalf("unexpected FCM message: %+v", msg)
+	}
+	if tokensIssued.Load() != 1 {
+		t.Fatalf("expected the access token to be reused, got %d", tokensIssued.Load())
+	}
+	resp := graphQL(t, r, token, `{ deviceTokens{ id platform } }`, nil)
+	devices := resp["data"].(map[string]any)["deviceTokens"].([]any)
+	// Registering good-token again made it the newest.
+	if len(devices) != 2 || devices[0].(map[string]any)["id"] != ids["flaky-token"] || devices[1].(map[string]any)["id"] != ids["good-token"] {
+		t.Fatalf("expected only the stale and invalid devices to be deleted, got %v", resp)
+	}
+	pending, _ := s.ListJobs(models.JobPending, 0)
+	if len(pending) != 1 || pending[0].Kind != worker.JobSendDevicePush || pending[0].Payload["deviceId"] != ids["flaky-token"] ||
+		!strings.Contains(pending[0].LastError, "503") {
+		t.Fatalf("expected the failed push to be retried, got %+v", pending)
+	}
+
+	// Failing to get an access token is not FCM rejecting the message, so
+	// the push is retried.
+	var unauthorized push.ServiceAccount
+	json.Unmarshal(sa, &unauthorized)
+	unauthorized.TokenURI = fake.URL + "/revoked"
+	noToken, _ := push.NewFCM(unauthorized)
+	noToken.Endpoint = fake.URL
+	var status *push.StatusError
+	if err := noToken.Send(context.Background(), "good-token", push.Message{}); err == nil || errors.As(err, &status) {
+		t.Fatalf("expected a retryable token error, got %v", err)
+	}
+
+	del := `mutation($id: ID!){ deleteDeviceToken(id: $id) }`
+	if resp := graphQL(t, r, token, del, map[string]any{"id": ids["good-token"]}); resp["data"].(map[string]any)["deleteDeviceToken"] != true {
+		t.Fatalf("expected the device to be deleted, got %v", resp)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	type delivery struct {
+		path, event, id, signature string
+		body                       []byte
+	}
+	var mu sync.Mutex
+	var received []delivery
+	failures := 2
+	stub := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, delivery{r.URL.Path, r.Header.Get(webhook.EventHeader), r.Header.Get(webhook.DeliveryHeader),
+			r.Header.Get(webhook.SignatureHeader), body})
+		if r.URL.Path == "/flaky" && failures > 0 {
+			failures--
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+		}
+	}))
+	defer stub.Close()
+	deliveries := func(path string) []delivery {
+		mu.Lock()
+		defer mu.Unlock()
+		var res []delivery
+		for _, d := range received {
+			if d.path == path {
+				res = append(res, d)
+			}
+		}
+		return res
+	}
+
+	server.Webhooks = &webhook.Sender{Client: stub.Client()}
+	t.Cleanup(func() { server.Webhooks = nil })
+	ps := store.NewPublishingStore(store.NewInMemoryStore(), pubsub.New())
+	r := server.SetupRouter(ps)
+	prefs := models.DefaultPreferences()
+	ps.CreateUser(models.User{ID: "hook-user", Email: "hook@example.com", IsVerified: true, Preferences: &prefs})
+	token, _ := auth.GenerateAccessToken("hook-user")
+
+	create := `mutation($input: WebhookInput!){ createWebhook(input: $input){ id url events secret active } }`
+	for _, bad := range []map[string]any{
+		{"url": "http://hooks.example.com/studybuddy", "events": []string{"TASK_CREATED"}},
+		{"url": stub.URL + "/hook", "events": []string{}},
+		{"url": stub.URL + "/hook", "events": []string{"TASK_CREATED"}},
+		{"url": "https://[::1]/hook", "events": []string{"TASK_CREATED"}},
+	} {
+		if resp := graphQL(t, r, token, create, map[string]any{"input": bad}); resp["errors"] == nil {
+			t.Fatalf("expected an invalid webhook to be rejected: %v", bad)
+		}
+	}
+	// Nor are webhooks on the server's own network delivered to.
+	if d := (&webhook.Sender{}).Deliver(context.Background(), models.Webhook{URL: stub.URL + "/hook"}, "evt", "ping", []byte("{}"), 1); d.Succeeded() ||
+		!strings.Contains(d.Error, netguard.ErrNotPublic.Error()) {
+		t.Fatalf("expected a delivery to a loopback address to be refused, got %+v", d)
+	}
+	if len(deliveries("/hook")) != 0 {
+		t.Fatal("expected no delivery to reach the stub")
+	}
+
+	// The stub is on a loopback address, which only local development may use.
+	t.Setenv("ALLOW_PRIVATE_HOSTS", "true")
+	resp := graphQL(t, r, token, create, map[string]any{"input": map[string]any{
+		"url": stub.URL + "/hook", "events": []string{"TASK_CREATED", "TASK_COMPLETED", "NOTIFICATION_CREATED", "TASK_CREATED"},
+	}})
+	hook := resp["data"].(map[string]any)["createWebhook"].(map[string]any)
+	secret, _ := hook["secret"].(string)
+	if !strings.HasPrefix(secret, "whsec_") || hook["active"] != true || len(hook["events"].([]any)) != 3 {
+		t.Fatalf("unexpected webhook: %v", resp)
+	}
+	resp = graphQL(t, r, token, create, map[string]any{"input": map[string]any{"url": stub.URL + "/flaky", "events": []string{"TASK_CREATED"}}})
+	flaky := resp["data"].(map[string]any)["createWebhook"].(map[string]any)
+
+	// A test sends a signed ping at once.
+	resp = graphQL(t, r, token, `mutation($id: ID!){ testWebhook(id: $id){ event statusCode succeeded error } }`, map[string]any{"id": hook["id"]})
+	if ping := resp["data"].(map[string]any)["testWebhook"].(map[string]any); ping["event"] != "ping" || ping["statusCode"] != float64(200) ||
+		ping["succeeded"] != true || ping["error"] != nil {
+		t.Fatalf("unexpected test delivery: %v", resp)
+	}
+	if got := deliveries("/hook"); len(got) != 1 || got[0].event != "ping" ||
+		webhook.Verify(secret, got[0].signature, got[0].body, webhook.DefaultTolerance, time.Now()) != nil {
+		t.Fatalf("expected a signed ping, got %+v", got)
+	}
+
+	// Changes are delivered by the worker to the webhooks subscribed to them.
+	task := ps.CreateTask(models.Task{Title: "Essay", UserID: "hook-user"})
+	task.Completed = true
+	ps.UpdateTask(task.ID, task)
+	ps.UpdateTask(task.ID, task)
+	quiz := ps.CreateTask(models.Task{Title: "Quiz", UserID: "hook-user", DueAt: time.Now().Add(2 * time.Hour), HasReminder: true})
+	e := ps.CreateEvent(models.Event{Title: "Lecture", UserID: "hook-user"})
+	ps.UpdateEvent(e.ID, e)
+	w := worker.NewWorker(ps)
+	w.Webhooks = server.Webhooks
+	w.Tick()
+	got := deliveries("/hook")
+	var events []string
+	for _, d := range got[1:] {
+		if err := webhook.Verify(secret, d.signature, d.body, webhook.DefaultTolerance, time.Now()); err != nil {
+			t.Fatalf("expected %s to be signed: %v", d.event, err)
+		}
+		events = append(events, d.event)
+	}
+	if strings.Join(events, ",") != "task.created,task.completed,task.created,notification.created" {
+		t.Fatalf("unexpected deliveries: %v", events)
+	}
+	var body struct {
+		ID   string
+		Type string
+		Data struct{ Message, ReferenceID string }
+	}
+	if err := json.Unmarshal(got[4].body, &body); err != nil || body.ID != got[4].id || body.Type != "notification.created" ||
+		body.Data.ReferenceID != quiz.ID || !strings.HasPrefix(body.Data.Message, "Task 'Quiz' is due") {
+		t.Fatalf("unexpected notification event %s: %v", got[4].body, err)
+	}
+	if webhook.Verify("whsec_wrong", got[1].signature, got[1].body, webhook.DefaultTolerance, time.Now()) == nil ||
+		webhook.Verify(secret, got[1].signature, got[1].body, webhook.DefaultTolerance, time.Now().Add(time.Hour)) == nil {
+		t.Fatal("expected a wrong secret or an old signature to be rejected")
+	}
+
+	// A failed delivery is logged and retried with backoff.
+	deliveriesQuery := `{ webhooks{ id deliveries{ event eventId attempt statusCode succeeded } } }`
+	resp = graphQL(t, r, token, deliveriesQuery, nil)
+	logged := resp["data"].(map[string]any)["webhooks"].([]any)[1].(map[string]any)["deliveries"].([]any)
+	if len(logged) != 2 || logged[0].(map[string]any)["statusCode"] != float64(500) || logged[0].(map[string]any)["succeeded"] != false {
+		t.Fatalf("expected a failed delivery to be logged, got %v", resp)
+	}
+	w.Tick()
+	if len(deliveries("/flaky")) != 2 {
+		t.Fatal("expected the retries to wait for their backoff")
+	}
+	w.Now = func() time.Time { return time.Now().Add(time.Minute) }
+	w.Tick()
+	if got := deliveries("/flaky"); len(got) != 4 || got[2].id != got[0].id || got[3].id != got[1].id {
+		t.Fatalf("expected the events to be delivered again, got %+v", got)
+	}
+	resp = graphQL(t, r, token, deliveriesQuery, nil)
+	logged = resp["data"].(map[string]any)["webhooks"].([]any)[1].(map[string]any)["deliveries"].([]any)
+	if len(logged) != 4 || logged[0].(map[string]any)["attempt"] != float64(2) || logged[0].(map[string]any)["succeeded"] != true {
+		t.Fatalf("expected the retry to be logged, got %v", resp)
+	}
+
+	// Turned off webhooks get nothing; other users cannot change them.
+	update := `mutation($id: ID!, $input: WebhookInput!){ updateWebhook(id: $id, input: $input){ active } }`
+	off := map[string]any{"id": flaky["id"], "input": map[string]any{"url": stub.URL + "/flaky", "events": []string{"TASK_CREATED"}, "active": false}}
+	ps.CreateUser(models.User{ID: "other-hook-user", Email: "other-hook@example.com", IsVerified: true})
+	other, _ := auth.GenerateAccessToken("other-hook-user")
+	if resp := graphQL(t, r, other, update, off); resp["errors"] == nil {
+		t.Fatal("expected another user not to update the webhook")
+	}
+	resp = graphQL(t, r, token, update, off)
+	if resp["data"].(map[string]any)["updateWebhook"].(map[string]any)["active"] != false {
+		t.Fatalf("expected the webhook to be turned off, got %v", resp)
+	}
+	ps.CreateTask(models.Task{Title: "Lab", UserID: "hook-user"})
+	w.Tick()
+	if len(deliveries("/flaky")) != 4 || len(deliveries("/hook")) != 6 {
+		t.Fatalf("expected only the active webhook to get the task, got %d and %d", len(deliveries("/flaky")), len(deliveries("/hook")))
+	}
+	resp = graphQL(t, r, token, `mutation($id: ID!){ deleteWebhook(id: $id) }`, map[string]any{"id": hook["id"]})
+	if resp["data"].(map[string]any)["deleteWebhook"] != true {
+		t.Fatalf("expected the webhook to be deleted, got %v", resp)
+	}
+}
+
+func TestTaskChangedSubscription(t *testing.T) {
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	server.SeedStore(s)
+	ts := httptest.NewServer(server.SetupRouter(s))
+	defer ts.Close()
+	token, _ := auth.GenerateAccessToken("test-user-id")
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/query", nil)
+	if err != nil {
+		t.Fatalf("failed to dial websocket: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	send := func(msg map[string]any) {
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("failed to write %v: %v", msg, err)
+		}
+	}
+	read := func(wantType string) map[string]any {
+		for {
+			var msg map[string]any
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("failed to read %s message: %v", wantType, err)
+			}
+			if msg["type"] == "ping" || msg["type"] == "pong" {
+				continue
+			}
+			if msg["type"] != wantType {
+				t.Fatalf("expected %s message, got %v", wantType, msg)
+			}
+			return msg
+		}
+	}
+
+	send(map[string]any{"type": "connection_init", "payload": map[string]any{"Authorization": "Bearer " + token}})
+	read("connection_ack")
+	send(map[string]any{"id": "1", "type": "subscribe", "payload": map[string]any{
+		"query": `subscription { taskChanged { action task { title } } }`,
+	}})
+
+	// The subscription is registered asynchronously; retry the mutation until an event arrives.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := 0; i < 50; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/query", strings.NewReader(
+				`{"query":"mutation { createTask(input:{title:\"Live\", description:\"\", courseId:\"course-1\", dueDate:\"2025-12-01\", dueTime:\"10:00\", hasReminder:false}){ id } }"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+
+	msg := read("next")
+	change := msg["payload"].(map[string]any)["data"].(map[string]any)["taskChanged"].(map[string]any)
+	if change["action"] != "CREATED" || change["task"].(map[string]any)["title"] != "Live" {
+		t.Fatalf("unexpected task change: %v", change)
+	}
+}
+
+func TestGraphQLCostLimits(t *testing.T) {
+	t.Setenv("GRAPHQL_MAX_COMPLEXITY", "100")
+	t.Setenv("GRAPHQL_MAX_DEPTH", "3")
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	server.SeedStore(s)
+	r := server.SetupRouter(s)
+	token, _ := auth.GenerateAccessToken("test-user-id")
+
+	resp := graphQL(t, r, token, `{ tasks { id } }`, nil)
+	if resp["errors"] != nil {
+		t.Fatalf("expected cheap query to succeed: %v", resp)
+	}
+
+	resp = graphQL(t, r, token, `{ tasks { id title course { name } } }`, nil)
+	if !strings.Contains(fmt.Sprint(resp["errors"]), "complexity") {
+		t.Fatalf("expected complexity error, got %v", resp)
+	}
+
+	resp = graphQL(t, r, token, `{ tasksConnection(first: 1) { edges { node { course { name } } } } }`, nil)
+	if !strings.Contains(fmt.Sprint(resp["errors"]), "DEPTH_LIMIT_EXCEEDED") {
+		t.Fatalf("expected depth error, got %v", resp)
+	}
+}
+
+func TestGraphQLRateLimit(t *testing.T) {
+	t.Setenv("RATE_LIMIT_USER_PER_MINUTE", "2")
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	r := server.SetupRouter(s)
+	token, _ := auth.GenerateAccessToken("limited-user-id")
+
+	for i := 0; i < 2; i++ {
+		if resp := graphQL(t, r, token, `{ tasks { id } }`, nil); resp["errors"] != nil {
+			t.Fatalf("request %d should be allowed: %v", i, resp)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(`{"query":"{ tasks { id } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), `"code":"RATE_LIMITED"`) || rr.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected structured RATE_LIMITED error, got %s", rr.Body.String())
+	}
+}
+
+// errorExtensions returns the extensions of the first GraphQL error in resp.
+func errorExtensions(t *testing.T, resp map[string]any) map[string]any {
+	t.Helper()
+	errs, ok := resp["errors"].([]any)
+	if !ok || len(errs) == 0 {
+		t.Fatalf("expected an error, got %v", resp)
+	}
+	ext, _ := errs[0].(map[string]any)["extensions"].(map[string]any)
+	return ext
+}
+
+func TestGraphQLErrorCodes(t *testing.T) {
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	server.SeedStore(s)
+	r := server.SetupRouter(s)
+	token, _ := auth.GenerateAccessToken("test-user-id")
+	other, _ := auth.GenerateAccessToken("someone-else")
+
+	if ext := errorExtensions(t, graphQL(t, r, "", `{ tasks { id } }`, nil)); ext["code"] != "UNAUTHENTICATED" {
+		t.Fatalf("expected UNAUTHENTICATED, got %v", ext)
+	}
+	if ext := errorExtensions(t, graphQL(t, r, token, `{ getTask(id: "missing") { id } }`, nil)); ext["code"] != "NOT_FOUND" {
+		t.Fatalf("expected NOT_FOUND, got %v", ext)
+	}
+	if ext := errorExtensions(t, graphQL(t, r, other, `{ getTask(id: "task-1") { id } }`, nil)); ext["code"] != "FORBIDDEN" {
+		t.Fatalf("expected FORBIDDEN, got %v", ext)
+	}
+
+	resp := graphQL(t, r, token, `mutation($input: NewTaskInput!){ createTask(input: $input){ id } }`, map[string]any{
+		"input": map[string]any{"title": "Essay", "description": "", "courseId": "course-1", "dueDate": "12/10/2025", "dueTime": "23:00", "hasReminder": false},
+	})
+	ext := errorExtensions(t, resp)
+	fields, _ := ext["fields"].(map[string]any)
+	if ext["code"] != "VALIDATION_FAILED" || fields["dueDate"] == nil || fields["title"] != nil {
+		t.Fatalf("expected dueDate validation error, got %v", ext)
+	}
+
+	resp = graphQL(t, r, token, `mutation{ createEvent(input: {title: "Lab", date: "2025-12-01", startTime: "10:00", endTime: "09:00", type: "CLASS"}){ id } }`, nil)
+	if fields, _ := errorExtensions(t, resp)["fields"].(map[string]any); fields["endTime"] == nil {
+		t.Fatalf("expected endTime validation error, got %v", resp)
+	}
+
+	resp = graphQL(t, r, token, `mutation{ createCourse(input: {name: "Art", color: "blue"}){ id } }`, nil)
+	if fields, _ := errorExtensions(t, resp)["fields"].(map[string]any); fields["color"] == nil {
+		t.Fatalf("expected color validation error, got %v", resp)
+	}
+
+	resp = graphQL(t, r, "", `mutation{ register(input: {name: "A", email: "not-an-email", password: "short"}){ token } }`, nil)
+	if fields, _ := errorExtensions(t, resp)["fields"].(map[string]any); fields["email"] == nil || fields["password"] == nil {
+		t.Fatalf("expected email and password validation errors, got %v", resp)
+	}
+
+	// Unexpected errors are logged, not shown to clients.
+	gqlErr := graph.ErrorPresenter(ctx, errors.New("dial tcp 10.0.0.5:27017: connection refused"))
+	if gqlErr.Message != "internal server error" || gqlErr.Extensions["code"] != "INTERNAL_SERVER_ERROR" {
+		t.Fatalf("expected a generic internal error, got %v %v", gqlErr.Message, gqlErr.Extensions)
+	}
+}
+
+func TestPersistedQueryAllowlist(t *testing.T) {
+	registered := `query { tasks { id } }`
+	sum := sha256.Sum256([]byte(registered))
+	hash := hex.EncodeToString(sum[:])
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(manifest, []byte(`{"`+hash+`": "`+registered+`"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PERSISTED_QUERIES_MANIFEST", manifest)
+	t.Setenv("PERSISTED_QUERIES_STRICT", "1")
+
+	ctx := context.Background()
+	s, _ := store.NewStore(ctx, "")
+	server.SeedStore(s)
+	r := server.SetupRouter(s)
+	token, _ := auth.GenerateAccessToken("test-user-id")
+
+	// Registered operations can be sent by hash alone.
+	body, _ := json.Marshal(map[string]any{
+		"extensions": map[string]any{"persistedQuery": map[string]any{"version": 1, "sha256Hash": hash}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	var resp map[string]any
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp["errors"] != nil || resp["data"].(map[string]any)["tasks"] == nil {
+		t.Fatalf("expected registered operation to run, got %s", rr.Body.String())
+	}
+
+	resp = graphQL(t, r, token, `{ courses { id } }`, nil)
+	if !strings.Contains(fmt.Sprint(resp["errors"]), "OPERATION_NOT_ALLOWED") {
+		t.Fatalf("expected unregistered operation to be rejected, got %v", resp)
+	}
+}
@@ -0,0 +1,27 @@
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/auth"
+)
+
+// requireAdmin returns an error unless the caller is signed in as a verified
+// user whose email is one of the resolver's admins.
+func (r *Resolver) requireAdmin(ctx context.Context) error {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return ErrUnauthenticated
+	}
+	u, err := r.Store.GetUser(userID)
+	if err != nil || !u.IsVerified {
+		return ErrForbidden
+	}
+	for _, admin := range r.Admins {
+		if strings.EqualFold(admin, u.Email) {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
@@ -0,0 +1,57 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/graph/model"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+)
+
+// unpagedListSize is the number of items assumed for list fields that have
+// no page size argument when estimating query cost.
+const unpagedListSize = 50
+
+// Complexity returns the per-field costs used by the complexity limit.
+// Lists multiply the cost of their selection by the number of items they may
+// return, and fields resolved through a separate store lookup cost extra.
+func Complexity() ComplexityRoot {
+	var c ComplexityRoot
+
+	list := func(childComplexity int) int { return 1 + childComplexity*unpagedListSize }
+	lookup := func(childComplexity int) int { return 2 + childComplexity }
+
+	c.Query.Tasks = list
+	c.Query.Courses = list
+	c.Query.Events = func(childComplexity int, _, _ *time.Time, _, _ *string) int {
+		return list(childComplexity)
+	}
+	c.Query.Notifications = func(childComplexity int, _ *bool) int {
+		return list(childComplexity)
+	}
+	c.Query.TasksConnection = func(childComplexity int, first *int, _ *string, _ *model.TaskFilter, _ *model.TaskOrder) int {
+		return 1 + childComplexity*pageCost(first)
+	}
+	c.Query.NotificationsConnection = func(childComplexity int, first *int, _ *string, _ *bool) int {
+		return 1 + childComplexity*pageCost(first)
+	}
+	c.Query.FailedJobs = func(childComplexity int, first *int) int {
+		return 1 + childComplexity*pageCost(first)
+	}
+
+	c.Task.Course = lookup
+	c.Event.Course = lookup
+	c.Course.TotalTasks = lookup
+	c.Course.CompletedTasks = lookup
+	return c
+}
+
+// pageCost is the number of items a paginated field may return.
+func pageCost(first *int) int {
+	switch {
+	case first == nil || *first <= 0:
+		return store.DefaultPageSize
+	case *first > store.MaxPageSize:
+		return store.MaxPageSize
+	}
+	return *first
+}
@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/RandithaK/StudyBuddy_Backend/graph/model"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Error codes reported to clients in extensions.code.
+const (
+	CodeUnauthenticated  = "UNAUTHENTICATED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeNotFound         = "NOT_FOUND"
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeInternal         = "INTERNAL_SERVER_ERROR"
+)
+
+// Error is an error whose code and message are safe to show to clients.
+type Error struct {
+	Code    string
+	Message string
+	// Fields maps invalid input fields to what is wrong with them.
+	Fields map[string]string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+var (
+	ErrUnauthenticated = &Error{Code: CodeUnauthenticated, Message: "authentication required"}
+	ErrForbidden       = &Error{Code: CodeForbidden, Message: "access denied"}
+
+	errInvalidCredentials = &Error{Code: CodeUnauthenticated, Message: "invalid credentials"}
+)
+
+// invalidField reports a single invalid input field.
+func invalidField(field, message string) *Error {
+	return &Error{
+		Code:    CodeValidationFailed,
+		Message: fmt.Sprintf("%s %s", field, message),
+		Fields:  map[string]string{field: message},
+	}
+}
+
+// ErrorPresenter maps resolver errors to GraphQL errors carrying
+// extensions.code, so clients can branch on the code instead of the message.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	if _, ok := gqlErr.Extensions["code"]; ok {
+		return gqlErr
+	}
+
+	var e *Error
+	var scalarErr *model.InvalidScalarError
+	switch {
+	case errors.As(err, &e):
+		gqlErr.Message = e.Message
+		setExtension(gqlErr, "code", e.Code)
+		if len(e.Fields) > 0 {
+			setExtension(gqlErr, "fields", e.Fields)
+		}
+	case errors.As(err, &scalarErr):
+		// The scalar's input field is the last element of the error's path.
+		field := "input"
+		if n := len(gqlErr.Path); n > 0 {
+			if name, ok := gqlErr.Path[n-1].(ast.PathName); ok {
+				field = string(name)
+			}
+		}
+		setExtension(gqlErr, "code", CodeValidationFailed)
+		setExtension(gqlErr, "fields", map[string]string{field: scalarErr.Reason})
+	case errors.Is(err, store.ErrNotFound):
+		setExtension(gqlErr, "code", CodeNotFound)
+	case errors.Is(err, store.ErrInvalidCursor):
+		setExtension(gqlErr, "code", CodeValidationFailed)
+		setExtension(gqlErr, "fields", map[string]string{"after": "is not a valid cursor"})
+	default:
+		// Unexpected errors may reveal internals, so clients only see that
+		// something went wrong
+		log.Printf("Error resolving %s: %v", gqlErr.Path, err)
+		gqlErr.Message = "internal server error"
+		setExtension(gqlErr, "code", CodeInternal)
+	}
+	return gqlErr
+}
+
+func setExtension(e *gqlerror.Error, key string, value any) {
+	if e.Extensions == nil {
+		e.Extensions = map[string]any{}
+	}
+	e.Extensions[key] = value
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/dataloader"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+)
+
+type loadersKey struct{}
+
+// Loaders hold the per-request dataloaders used by field resolvers.
+type Loaders struct {
+	Courses    *dataloader.Loader[string, *models.Course]
+	TaskCounts *dataloader.Loader[string, store.TaskCounts]
+	// Users are the owners of tasks and events, whose time zones and
+	// preferences decide how the items are shown.
+	Users *dataloader.Loader[string, *models.User]
+}
+
+func NewLoaders(s store.Store) *Loaders {
+	return &Loaders{
+		Courses: dataloader.New(func(ctx context.Context, ids []string) ([]*models.Course, []error) {
+			courses, err := s.GetCoursesByIDs(ids)
+			if err != nil {
+				return nil, []error{err}
+			}
+			byID := make(map[string]*models.Course, len(courses))
+			for i := range courses {
+				byID[courses[i].ID] = &courses[i]
+			}
+			res := make([]*models.Course, len(ids))
+			for i, id := range ids {
+				res[i] = byID[id]
+			}
+			return res, nil
+		}),
+		TaskCounts: dataloader.New(func(ctx context.Context, courseIDs []string) ([]store.TaskCounts, []error) {
+			counts, err := s.CountTasksByCourse(courseIDs)
+			if err != nil {
+				return nil, []error{err}
+			}
+			res := make([]store.TaskCounts, len(courseIDs))
+			for i, id := range courseIDs {
+				res[i] = counts[id]
+			}
+			return res, nil
+		}),
+		Users: dataloader.New(func(ctx context.Context, userIDs []string) ([]*models.User, []error) {
+			res := make([]*models.User, len(userIDs))
+			for i, id := range userIDs {
+				if u, err := s.GetUser(id); err == nil {
+					res[i] = &u
+				}
+			}
+			return res, nil
+		}),
+	}
+}
+
+// LoaderMiddleware attaches a fresh set of loaders to every request so that
+// cached values never leak between requests or users. Websocket connections
+// are skipped: they outlive a single operation, so their resolvers fall back
+// to unshared loaders rather than caching for the connection's lifetime.
+func LoaderMiddleware(s store.Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := context.WithValue(r.Context(), loadersKey{}, NewLoaders(s))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// loadersFor returns the request's loaders, or a fresh unshared set when the
+// resolver runs outside LoaderMiddleware.
+func (r *Resolver) loadersFor(ctx context.Context) *Loaders {
+	if l, ok := ctx.Value(loadersKey{}).(*Loaders); ok {
+		return l
+	}
+	return NewLoaders(r.Store)
+}
@@ -3,6 +3,13 @@
 package model
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
 )
 
@@ -22,6 +29,24 @@ type ChangePasswordPayload struct {
 	Message string `json:"message"`
 }
 
+type DeviceTokenInput struct {
+	// The FCM registration token the app got on the device.
+	Token    string         `json:"token"`
+	Platform DevicePlatform `json:"platform"`
+	// A label for the device, e.g. Pixel 8.
+	Device *string `json:"device,omitempty"`
+}
+
+type DigestInput struct {
+	Frequency DigestFrequency `json:"frequency"`
+	At        TimeOfDay       `json:"at"`
+}
+
+type EventChange struct {
+	Action ChangeAction  `json:"action"`
+	Event  *models.Event `json:"event"`
+}
+
 type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -36,46 +61,741 @@ type NewCourseInput struct {
 }
 
 type NewEventInput struct {
-	Title       string  `json:"title"`
-	Description *string `json:"description,omitempty"`
-	CourseID    *string `json:"courseId,omitempty"`
-	Date        string  `json:"date"`
-	StartTime   string  `json:"startTime"`
-	EndTime     string  `json:"endTime"`
-	Type        string  `json:"type"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description,omitempty"`
+	CourseID    *string   `json:"courseId,omitempty"`
+	Date        time.Time `json:"date"`
+	StartTime   TimeOfDay `json:"startTime"`
+	EndTime     TimeOfDay `json:"endTime"`
+	Type        string    `json:"type"`
+	// Replaces the default reminder. An empty list turns reminders off.
+	Reminders []*ReminderInput `json:"reminders,omitempty"`
 }
 
 type NewTaskInput struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	CourseID    string `json:"courseId"`
-	DueDate     string `json:"dueDate"`
-	DueTime     string `json:"dueTime"`
-	HasReminder bool   `json:"hasReminder"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CourseID    string    `json:"courseId"`
+	DueDate     time.Time `json:"dueDate"`
+	DueTime     TimeOfDay `json:"dueTime"`
+	HasReminder bool      `json:"hasReminder"`
+	// Replaces the default reminder. Ignored unless hasReminder is set.
+	Reminders []*ReminderInput `json:"reminders,omitempty"`
+}
+
+type NotificationConnection struct {
+	Edges      []*NotificationEdge `json:"edges"`
+	PageInfo   *PageInfo           `json:"pageInfo"`
+	TotalCount int                 `json:"totalCount"`
+}
+
+type NotificationEdge struct {
+	Cursor string               `json:"cursor"`
+	Node   *models.Notification `json:"node"`
+}
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
+// Omitted fields are left unchanged. Set quietHours or digest to null to turn them off.
+type PreferencesInput struct {
+	TaskReminderLeadMinutes  *int                                `json:"taskReminderLeadMinutes,omitempty"`
+	EventReminderLeadMinutes *int                                `json:"eventReminderLeadMinutes,omitempty"`
+	EmailDelayMinutes        *int                                `json:"emailDelayMinutes,omitempty"`
+	QuietHours               graphql.Omittable[*QuietHoursInput] `json:"quietHours,omitempty"`
+	Channels                 []NotificationChannel               `json:"channels,omitempty"`
+	WeekStart                *Weekday                            `json:"weekStart,omitempty"`
+	Locale                   *string                             `json:"locale,omitempty"`
+	Digest                   graphql.Omittable[*DigestInput]     `json:"digest,omitempty"`
+}
+
+// The fields of a browser PushSubscription: its endpoint and keys.p256dh and keys.auth, base64url encoded.
+type PushSubscriptionInput struct {
+	Endpoint string `json:"endpoint"`
+	P256dh   string `json:"p256dh"`
+	Auth     string `json:"auth"`
+	// A label for the device, e.g. Firefox on Android.
+	Device *string `json:"device,omitempty"`
 }
 
 type Query struct {
 }
 
+type QuietHoursInput struct {
+	Start TimeOfDay `json:"start"`
+	End   TimeOfDay `json:"end"`
+}
+
 type RegisterInput struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// IANA time zone; defaults to UTC.
+	TimeZone *string `json:"timeZone,omitempty"`
+	// Language tag notifications and emails are shown in; defaults to en. en, si and ta are translated.
+	Locale *string `json:"locale,omitempty"`
+}
+
+type ReminderInput struct {
+	MinutesBefore *int       `json:"minutesBefore,omitempty"`
+	At            *time.Time `json:"at,omitempty"`
+}
+
+// Live updates for the authenticated user. Over websockets, authenticate by
+// sending the access token as "Authorization: Bearer <token>" (or "authToken")
+// in the connection_init payload.
+type Subscription struct {
+}
+
+type TaskChange struct {
+	Action ChangeAction `json:"action"`
+	Task   *models.Task `json:"task"`
+}
+
+type TaskConnection struct {
+	Edges      []*TaskEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int         `json:"totalCount"`
+}
+
+type TaskEdge struct {
+	Cursor string       `json:"cursor"`
+	Node   *models.Task `json:"node"`
+}
+
+type TaskFilter struct {
+	CourseID  *string `json:"courseId,omitempty"`
+	Completed *bool   `json:"completed,omitempty"`
+	// Inclusive lower bound on dueDate.
+	DueAfter *time.Time `json:"dueAfter,omitempty"`
+	// Inclusive upper bound on dueDate.
+	DueBefore   *time.Time `json:"dueBefore,omitempty"`
+	HasReminder *bool      `json:"hasReminder,omitempty"`
+	Overdue     *bool      `json:"overdue,omitempty"`
+}
+
+type TaskOrder struct {
+	Field     TaskSortField  `json:"field"`
+	Direction *SortDirection `json:"direction,omitempty"`
+}
+
+type UpdateEventInput struct {
+	ID          string     `json:"id"`
+	Title       *string    `json:"title,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	CourseID    *string    `json:"courseId,omitempty"`
+	Date        *time.Time `json:"date,omitempty"`
+	StartTime   *TimeOfDay `json:"startTime,omitempty"`
+	EndTime     *TimeOfDay `json:"endTime,omitempty"`
+	Type        *string    `json:"type,omitempty"`
+	// Replaces the event's reminders. An empty list turns them off.
+	Reminders []*ReminderInput `json:"reminders,omitempty"`
 }
 
 type UpdateTaskInput struct {
-	ID          string  `json:"id"`
-	Title       *string `json:"title,omitempty"`
-	Description *string `json:"description,omitempty"`
-	CourseID    *string `json:"courseId,omitempty"`
-	DueDate     *string `json:"dueDate,omitempty"`
-	DueTime     *string `json:"dueTime,omitempty"`
-	Completed   *bool   `json:"completed,omitempty"`
-	HasReminder *bool   `json:"hasReminder,omitempty"`
-	CompletedAt *string `json:"completedAt,omitempty"`
+	ID          string     `json:"id"`
+	Title       *string    `json:"title,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	CourseID    *string    `json:"courseId,omitempty"`
+	DueDate     *time.Time `json:"dueDate,omitempty"`
+	DueTime     *TimeOfDay `json:"dueTime,omitempty"`
+	Completed   *bool      `json:"completed,omitempty"`
+	HasReminder *bool      `json:"hasReminder,omitempty"`
+	// Replaces the task's reminders. An empty list goes back to the default reminder.
+	Reminders []*ReminderInput `json:"reminders,omitempty"`
+	// When the task was completed, if not now. Only used when completed is true.
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
 }
 
 type UpdateUserInput struct {
-	Name  *string `json:"name,omitempty"`
-	Email *string `json:"email,omitempty"`
+	Name     *string `json:"name,omitempty"`
+	Email    *string `json:"email,omitempty"`
+	TimeZone *string `json:"timeZone,omitempty"`
+}
+
+type WebhookInput struct {
+	// An https URL.
+	URL         string         `json:"url"`
+	Events      []WebhookEvent `json:"events"`
+	Description *string        `json:"description,omitempty"`
+	// Whether events are delivered; true unless given.
+	Active *bool `json:"active,omitempty"`
+}
+
+type ChangeAction string
+
+const (
+	ChangeActionCreated ChangeAction = "CREATED"
+	ChangeActionUpdated ChangeAction = "UPDATED"
+	ChangeActionDeleted ChangeAction = "DELETED"
+)
+
+var AllChangeAction = []ChangeAction{
+	ChangeActionCreated,
+	ChangeActionUpdated,
+	ChangeActionDeleted,
+}
+
+func (e ChangeAction) IsValid() bool {
+	switch e {
+	case ChangeActionCreated, ChangeActionUpdated, ChangeActionDeleted:
+		return true
+	}
+	return false
+}
+
+func (e ChangeAction) String() string {
+	return string(e)
+}
+
+func (e *ChangeAction) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ChangeAction(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ChangeAction", str)
+	}
+	return nil
+}
+
+func (e ChangeAction) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ChangeAction) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ChangeAction) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type DevicePlatform string
+
+const (
+	DevicePlatformAndroid DevicePlatform = "ANDROID"
+	DevicePlatformIos     DevicePlatform = "IOS"
+)
+
+var AllDevicePlatform = []DevicePlatform{
+	DevicePlatformAndroid,
+	DevicePlatformIos,
+}
+
+func (e DevicePlatform) IsValid() bool {
+	switch e {
+	case DevicePlatformAndroid, DevicePlatformIos:
+		return true
+	}
+	return false
+}
+
+func (e DevicePlatform) String() string {
+	return string(e)
+}
+
+func (e *DevicePlatform) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DevicePlatform(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DevicePlatform", str)
+	}
+	return nil
+}
+
+func (e DevicePlatform) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *DevicePlatform) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e DevicePlatform) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type DigestFrequency string
+
+const (
+	DigestFrequencyDaily  DigestFrequency = "DAILY"
+	DigestFrequencyWeekly DigestFrequency = "WEEKLY"
+)
+
+var AllDigestFrequency = []DigestFrequency{
+	DigestFrequencyDaily,
+	DigestFrequencyWeekly,
+}
+
+func (e DigestFrequency) IsValid() bool {
+	switch e {
+	case DigestFrequencyDaily, DigestFrequencyWeekly:
+		return true
+	}
+	return false
+}
+
+func (e DigestFrequency) String() string {
+	return string(e)
+}
+
+func (e *DigestFrequency) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DigestFrequency(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DigestFrequency", str)
+	}
+	return nil
+}
+
+func (e DigestFrequency) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *DigestFrequency) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e DigestFrequency) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type JobStatus string
+
+const (
+	JobStatusPending JobStatus = "PENDING"
+	JobStatusRunning JobStatus = "RUNNING"
+	JobStatusDead    JobStatus = "DEAD"
+)
+
+var AllJobStatus = []JobStatus{
+	JobStatusPending,
+	JobStatusRunning,
+	JobStatusDead,
+}
+
+func (e JobStatus) IsValid() bool {
+	switch e {
+	case JobStatusPending, JobStatusRunning, JobStatusDead:
+		return true
+	}
+	return false
+}
+
+func (e JobStatus) String() string {
+	return string(e)
+}
+
+func (e *JobStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = JobStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid JobStatus", str)
+	}
+	return nil
+}
+
+func (e JobStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *JobStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e JobStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type NotificationChannel string
+
+const (
+	NotificationChannelInApp NotificationChannel = "IN_APP"
+	NotificationChannelEmail NotificationChannel = "EMAIL"
+	NotificationChannelPush  NotificationChannel = "PUSH"
+)
+
+var AllNotificationChannel = []NotificationChannel{
+	NotificationChannelInApp,
+	NotificationChannelEmail,
+	NotificationChannelPush,
+}
+
+func (e NotificationChannel) IsValid() bool {
+	switch e {
+	case NotificationChannelInApp, NotificationChannelEmail, NotificationChannelPush:
+		return true
+	}
+	return false
+}
+
+func (e NotificationChannel) String() string {
+	return string(e)
+}
+
+func (e *NotificationChannel) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NotificationChannel(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NotificationChannel", str)
+	}
+	return nil
+}
+
+func (e NotificationChannel) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *NotificationChannel) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e NotificationChannel) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type SortDirection string
+
+const (
+	SortDirectionAsc  SortDirection = "ASC"
+	SortDirectionDesc SortDirection = "DESC"
+)
+
+var AllSortDirection = []SortDirection{
+	SortDirectionAsc,
+	SortDirectionDesc,
+}
+
+func (e SortDirection) IsValid() bool {
+	switch e {
+	case SortDirectionAsc, SortDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e SortDirection) String() string {
+	return string(e)
+}
+
+func (e *SortDirection) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SortDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SortDirection", str)
+	}
+	return nil
+}
+
+func (e SortDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *SortDirection) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e SortDirection) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type SuppressionReason string
+
+const (
+	SuppressionReasonUnsubscribed SuppressionReason = "UNSUBSCRIBED"
+	SuppressionReasonBounced      SuppressionReason = "BOUNCED"
+	SuppressionReasonComplained   SuppressionReason = "COMPLAINED"
+)
+
+var AllSuppressionReason = []SuppressionReason{
+	SuppressionReasonUnsubscribed,
+	SuppressionReasonBounced,
+	SuppressionReasonComplained,
+}
+
+func (e SuppressionReason) IsValid() bool {
+	switch e {
+	case SuppressionReasonUnsubscribed, SuppressionReasonBounced, SuppressionReasonComplained:
+		return true
+	}
+	return false
+}
+
+func (e SuppressionReason) String() string {
+	return string(e)
+}
+
+func (e *SuppressionReason) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SuppressionReason(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SuppressionReason", str)
+	}
+	return nil
+}
+
+func (e SuppressionReason) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *SuppressionReason) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e SuppressionReason) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type TaskSortField string
+
+const (
+	TaskSortFieldDueDate   TaskSortField = "DUE_DATE"
+	TaskSortFieldCreatedAt TaskSortField = "CREATED_AT"
+	TaskSortFieldTitle     TaskSortField = "TITLE"
+)
+
+var AllTaskSortField = []TaskSortField{
+	TaskSortFieldDueDate,
+	TaskSortFieldCreatedAt,
+	TaskSortFieldTitle,
+}
+
+func (e TaskSortField) IsValid() bool {
+	switch e {
+	case TaskSortFieldDueDate, TaskSortFieldCreatedAt, TaskSortFieldTitle:
+		return true
+	}
+	return false
+}
+
+func (e TaskSortField) String() string {
+	return string(e)
+}
+
+func (e *TaskSortField) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TaskSortField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TaskSortField", str)
+	}
+	return nil
+}
+
+func (e TaskSortField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *TaskSortField) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e TaskSortField) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type WebhookEvent string
+
+const (
+	WebhookEventTaskCreated         WebhookEvent = "TASK_CREATED"
+	WebhookEventTaskCompleted       WebhookEvent = "TASK_COMPLETED"
+	WebhookEventEventUpdated        WebhookEvent = "EVENT_UPDATED"
+	WebhookEventNotificationCreated WebhookEvent = "NOTIFICATION_CREATED"
+)
+
+var AllWebhookEvent = []WebhookEvent{
+	WebhookEventTaskCreated,
+	WebhookEventTaskCompleted,
+	WebhookEventEventUpdated,
+	WebhookEventNotificationCreated,
+}
+
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case WebhookEventTaskCreated, WebhookEventTaskCompleted, WebhookEventEventUpdated, WebhookEventNotificationCreated:
+		return true
+	}
+	return false
+}
+
+func (e WebhookEvent) String() string {
+	return string(e)
+}
+
+func (e *WebhookEvent) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookEvent(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookEvent", str)
+	}
+	return nil
+}
+
+func (e WebhookEvent) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *WebhookEvent) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e WebhookEvent) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type Weekday string
+
+const (
+	WeekdaySunday    Weekday = "SUNDAY"
+	WeekdayMonday    Weekday = "MONDAY"
+	WeekdayTuesday   Weekday = "TUESDAY"
+	WeekdayWednesday Weekday = "WEDNESDAY"
+	WeekdayThursday  Weekday = "THURSDAY"
+	WeekdayFriday    Weekday = "FRIDAY"
+	WeekdaySaturday  Weekday = "SATURDAY"
+)
+
+var AllWeekday = []Weekday{
+	WeekdaySunday,
+	WeekdayMonday,
+	WeekdayTuesday,
+	WeekdayWednesday,
+	WeekdayThursday,
+	WeekdayFriday,
+	WeekdaySaturday,
+}
+
+func (e Weekday) IsValid() bool {
+	switch e {
+	case WeekdaySunday, WeekdayMonday, WeekdayTuesday, WeekdayWednesday, WeekdayThursday, WeekdayFriday, WeekdaySaturday:
+		return true
+	}
+	return false
+}
+
+func (e Weekday) String() string {
+	return string(e)
+}
+
+func (e *Weekday) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Weekday(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Weekday", str)
+	}
+	return nil
+}
+
+func (e Weekday) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *Weekday) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e Weekday) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
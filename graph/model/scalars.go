@@ -0,0 +1,101 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+const (
+	DateLayout = "2006-01-02"
+	TimeLayout = "15:04"
+)
+
+// InvalidScalarError is returned for scalar input that cannot be parsed.
+type InvalidScalarError struct {
+	Reason string
+}
+
+func (e *InvalidScalarError) Error() string { return e.Reason }
+
+func invalidScalar(format string, args ...any) error {
+	return &InvalidScalarError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// MarshalDate writes a Date scalar: the calendar date of t in t's location.
+func MarshalDate(t time.Time) graphql.Marshaler {
+	return graphql.MarshalString(t.Format(DateLayout))
+}
+
+// UnmarshalDate reads an ISO-8601 calendar date (YYYY-MM-DD) as midnight UTC.
+func UnmarshalDate(v any) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, invalidScalar("Date must be a string")
+	}
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return time.Time{}, invalidScalar("%q is not a date in YYYY-MM-DD format", s)
+	}
+	return t, nil
+}
+
+// MarshalDateTime writes a DateTime scalar in RFC 3339 format, always in UTC
+// so values do not depend on where they were loaded from.
+func MarshalDateTime(t time.Time) graphql.Marshaler {
+	return graphql.MarshalString(t.UTC().Format(time.RFC3339))
+}
+
+// UnmarshalDateTime reads an RFC 3339 timestamp, which must include an offset.
+func UnmarshalDateTime(v any) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, invalidScalar("DateTime must be a string")
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, invalidScalar("%q is not an RFC 3339 timestamp", s)
+	}
+	return t, nil
+}
+
+// TimeOfDay is a wall-clock time, the Go side of the Time scalar (HH:MM).
+type TimeOfDay struct {
+	Hour   int
+	Minute int
+}
+
+// ClockOf returns the time of day of t in t's location.
+func ClockOf(t time.Time) TimeOfDay {
+	return TimeOfDay{Hour: t.Hour(), Minute: t.Minute()}
+}
+
+// On returns the instant at this time of day on date's calendar day in loc.
+func (c TimeOfDay) On(date time.Time, loc *time.Location) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, c.Hour, c.Minute, 0, 0, loc)
+}
+
+func (c TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", c.Hour, c.Minute)
+}
+
+func (c TimeOfDay) MarshalGQL(w io.Writer) {
+	io.WriteString(w, strconv.Quote(c.String()))
+}
+
+func (c *TimeOfDay) UnmarshalGQL(v any) error {
+	s, ok := v.(string)
+	if !ok {
+		return invalidScalar("Time must be a string")
+	}
+	t, err := time.Parse(TimeLayout, s)
+	if err != nil {
+		return invalidScalar("%q is not a time in HH:MM format", s)
+	}
+	*c = ClockOf(t)
+	return nil
+}
@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/graph/model"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+)
+
+func toPageInfo(p store.PageInfo) *model.PageInfo {
+	info := &model.PageInfo{
+		HasNextPage:     p.HasNextPage,
+		HasPreviousPage: p.HasPreviousPage,
+	}
+	if p.StartCursor != "" {
+		info.StartCursor = &p.StartCursor
+	}
+	if p.EndCursor != "" {
+		info.EndCursor = &p.EndCursor
+	}
+	return info
+}
+
+// toTaskQuery builds a store query; filter dates are days in loc.
+func toTaskQuery(first *int, after *string, filter *model.TaskFilter, orderBy *model.TaskOrder, loc *time.Location) store.TaskQuery {
+	q := store.TaskQuery{
+		SortBy: store.TaskSortDueDate,
+		First:  derefInt(first),
+		After:  deref(after),
+	}
+	if filter != nil {
+		q.Filter = store.TaskFilter{
+			CourseID:    filter.CourseID,
+			Completed:   filter.Completed,
+			HasReminder: filter.HasReminder,
+			Overdue:     filter.Overdue,
+		}
+		q.Filter.DueFrom, q.Filter.DueTo = dayRange(filter.DueAfter, filter.DueBefore, loc)
+	}
+	if orderBy != nil {
+		q.SortBy = store.TaskSortField(orderBy.Field)
+		q.Descending = orderBy.Direction != nil && *orderBy.Direction == model.SortDirectionDesc
+	}
+	return q
+}
+
+func toTaskConnection(p store.TaskPage) *model.TaskConnection {
+	conn := &model.TaskConnection{
+		Edges:      make([]*model.TaskEdge, 0, len(p.Edges)),
+		PageInfo:   toPageInfo(p.PageInfo),
+		TotalCount: p.TotalCount,
+	}
+	for i := range p.Edges {
+		conn.Edges = append(conn.Edges, &model.TaskEdge{
+			Cursor: p.Edges[i].Cursor,
+			Node:   &p.Edges[i].Task,
+		})
+	}
+	return conn
+}
+
+func toNotificationConnection(p store.NotificationPage) *model.NotificationConnection {
+	conn := &model.NotificationConnection{
+		Edges:      make([]*model.NotificationEdge, 0, len(p.Edges)),
+		PageInfo:   toPageInfo(p.PageInfo),
+		TotalCount: p.TotalCount,
+	}
+	for i := range p.Edges {
+		conn.Edges = append(conn.Edges, &model.NotificationEdge{
+			Cursor: p.Edges[i].Cursor,
+			Node:   &p.Edges[i].Notification,
+		})
+	}
+	return conn
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func derefInt(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
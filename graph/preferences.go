@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/graph/model"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+)
+
+const maxEmailDelayMinutes = 24 * 60
+
+// minutesOf converts a stored duration to the whole minutes shown in the API.
+func minutesOf(d time.Duration) int {
+	return int(d / time.Minute)
+}
+
+// clockOfMinutes converts minutes after midnight to a time of day.
+func clockOfMinutes(m int) *model.TimeOfDay {
+	return &model.TimeOfDay{Hour: m / 60, Minute: m % 60}
+}
+
+// applyPreferences returns p with the fields set in input changed, or a
+// VALIDATION_FAILED error listing every invalid field.
+func applyPreferences(p models.Preferences, input model.PreferencesInput) (models.Preferences, error) {
+	var v validator
+	if input.TaskReminderLeadMinutes != nil {
+		m := *input.TaskReminderLeadMinutes
+		v.minutes("taskReminderLeadMinutes", m, minutesOf(models.MaxReminderLead))
+		p.TaskReminderLead = time.Duration(m) * time.Minute
+	}
+	if input.EventReminderLeadMinutes != nil {
+		m := *input.EventReminderLeadMinutes
+		v.minutes("eventReminderLeadMinutes", m, minutesOf(models.MaxReminderLead))
+		p.EventReminderLead = time.Duration(m) * time.Minute
+	}
+	if input.EmailDelayMinutes != nil {
+		m := *input.EmailDelayMinutes
+		v.minutes("emailDelayMinutes", m, maxEmailDelayMinutes)
+		p.EmailDelay = time.Duration(m) * time.Minute
+	}
+	if q, set := input.QuietHours.ValueOK(); set {
+		p.QuietHours = nil
+		if q != nil {
+			p.QuietHours = &models.QuietHours{
+				Start: q.Start.Hour*60 + q.Start.Minute,
+				End:   q.End.Hour*60 + q.End.Minute,
+			}
+		}
+	}
+	if d, set := input.Digest.ValueOK(); set {
+		p.Digest = nil
+		if d != nil {
+			p.Digest = &models.Digest{
+				Frequency: string(d.Frequency),
+				At:        d.At.Hour*60 + d.At.Minute,
+			}
+		}
+	}
+	if input.Channels != nil {
+		p.Channels = []string{}
+		for _, c := range input.Channels {
+			if !p.HasChannel(string(c)) {
+				p.Channels = append(p.Channels, string(c))
+			}
+		}
+	}
+	if input.WeekStart != nil {
+		for i, wd := range model.AllWeekday {
+			if wd == *input.WeekStart {
+				p.WeekStart = time.Weekday(i)
+			}
+		}
+	}
+	if input.Locale != nil {
+		v.locale("locale", *input.Locale)
+		p.Locale = *input.Locale
+	}
+	return p, v.err()
+}
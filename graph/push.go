@@ -0,0 +1,51 @@
+package graph
+
+import (
+	"crypto/ecdh"
+	"encoding/base64"
+	"net/url"
+
+	"github.com/RandithaK/StudyBuddy_Backend/graph/model"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/netguard"
+)
+
+// validatePushSubscription checks that input is a subscription Web Push can
+// send to: an https endpoint on a public host, a P-256 public key and a 16-byte auth secret.
+func validatePushSubscription(v *validator, input model.PushSubscriptionInput) {
+	if u, err := url.Parse(input.Endpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+		v.fail("endpoint", "must be an https URL")
+	} else if netguard.CheckHost(u.Hostname()) != nil {
+		v.fail("endpoint", "must be on a public host")
+	}
+	key, err := base64.RawURLEncoding.DecodeString(input.P256dh)
+	if err == nil {
+		_, err = ecdh.P256().NewPublicKey(key)
+	}
+	if err != nil {
+		v.fail("p256dh", "must be a base64url encoded P-256 public key")
+	}
+	if auth, err := base64.RawURLEncoding.DecodeString(input.Auth); err != nil || len(auth) != 16 {
+		v.fail("auth", "must be a base64url encoded 16-byte secret")
+	}
+	if input.Device != nil {
+		v.title("device", *input.Device)
+	}
+}
+
+// maxDeviceTokenLength bounds registration tokens, which FCM makes a few
+// hundred characters long.
+const maxDeviceTokenLength = 4096
+
+// validateDeviceToken checks that input is a device registration the push
+// provider can send to.
+func validateDeviceToken(v *validator, input model.DeviceTokenInput) {
+	if v.required("token", input.Token) && len(input.Token) > maxDeviceTokenLength {
+		v.fail("token", "must be at most 4096 characters")
+	}
+	if !input.Platform.IsValid() {
+		v.fail("platform", "must be ANDROID or IOS")
+	}
+	if input.Device != nil {
+		v.title("device", *input.Device)
+	}
+}
@@ -0,0 +1,46 @@
+package graph
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/graph/model"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+)
+
+const maxReminders = 10
+
+// toReminders converts reminder input for an item due or starting at due.
+// An empty, non-nil input gives an empty, non-nil result.
+func toReminders(inputs []*model.ReminderInput, due time.Time) ([]models.Reminder, error) {
+	var v validator
+	if len(inputs) > maxReminders {
+		v.fail("reminders", fmt.Sprintf("must have at most %d reminders", maxReminders))
+	}
+	res := make([]models.Reminder, 0, len(inputs))
+	for i, in := range inputs {
+		field := fmt.Sprintf("reminders[%d]", i)
+		switch {
+		case (in.MinutesBefore == nil) == (in.At == nil):
+			v.fail(field, "must set exactly one of minutesBefore and at")
+		case in.MinutesBefore != nil:
+			v.minutes(field+".minutesBefore", *in.MinutesBefore, minutesOf(models.MaxReminderLead))
+			res = append(res, models.Reminder{Before: time.Duration(*in.MinutesBefore) * time.Minute})
+		default:
+			if in.At.After(due) || due.Sub(*in.At) > models.MaxReminderLead {
+				v.fail(field+".at", "must be within 7 days before the due or start time")
+			}
+			at := in.At.UTC()
+			res = append(res, models.Reminder{At: &at})
+		}
+	}
+	return res, v.err()
+}
+
+func reminderRefs(rs []models.Reminder) []*models.Reminder {
+	res := make([]*models.Reminder, len(rs))
+	for i := range rs {
+		res[i] = &rs[i]
+	}
+	return res
+}
@@ -1,6 +1,13 @@
 package graph
 
-import "github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+import (
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/email"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/pubsub"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/webhook"
+)
 
 // This file will not be regenerated automatically.
 //
@@ -8,5 +15,25 @@ import "github.com/RandithaK/StudyBuddy_Backend/pkg/store"
 // here.
 
 type Resolver struct {
-	Store store.Store
+	Store  store.Store
+	PubSub *pubsub.Broker
+	Mailer email.Mailer
+	// Admins are the email addresses of users allowed to use admin-only
+	// fields, once their address is verified.
+	Admins []string
+	// VAPIDPublicKey is the key browsers subscribe to Web Push with, or
+	// empty if Web Push is not configured.
+	VAPIDPublicKey string
+	// Webhooks sends test deliveries to webhooks; nil uses a Sender with
+	// the default client.
+	Webhooks *webhook.Sender
+	// Now returns the current time; nil means time.Now.
+	Now func() time.Time
+}
+
+func (r *Resolver) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
 }
@@ -7,39 +7,127 @@ package graph
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/RandithaK/StudyBuddy_Backend/graph/model"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/auth"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/email"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/i18n"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/pubsub"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/webhook"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// TotalTasks is the resolver for the totalTasks field.
+func (r *courseResolver) TotalTasks(ctx context.Context, obj *models.Course) (int, error) {
+	counts, err := r.loadersFor(ctx).TaskCounts.Load(ctx, obj.ID)
+	if err != nil {
+		return 0, err
+	}
+	return counts.Total, nil
+}
+
+// CompletedTasks is the resolver for the completedTasks field.
+func (r *courseResolver) CompletedTasks(ctx context.Context, obj *models.Course) (int, error) {
+	counts, err := r.loadersFor(ctx).TaskCounts.Load(ctx, obj.ID)
+	if err != nil {
+		return 0, err
+	}
+	return counts.Completed, nil
+}
+
+// Platform is the resolver for the platform field.
+func (r *deviceTokenResolver) Platform(ctx context.Context, obj *models.DeviceToken) (model.DevicePlatform, error) {
+	return model.DevicePlatform(obj.Platform), nil
+}
+
+// Frequency is the resolver for the frequency field.
+func (r *digestResolver) Frequency(ctx context.Context, obj *models.Digest) (model.DigestFrequency, error) {
+	return model.DigestFrequency(obj.Frequency), nil
+}
+
+// At is the resolver for the at field.
+func (r *digestResolver) At(ctx context.Context, obj *models.Digest) (*model.TimeOfDay, error) {
+	return clockOfMinutes(obj.At), nil
+}
+
+// Category is the resolver for the category field.
+func (r *emailSuppressionResolver) Category(ctx context.Context, obj *models.Suppression) (*string, error) {
+	if obj.Category == "" {
+		return nil, nil
+	}
+	return &obj.Category, nil
+}
+
+// Reason is the resolver for the reason field.
+func (r *emailSuppressionResolver) Reason(ctx context.Context, obj *models.Suppression) (model.SuppressionReason, error) {
+	return model.SuppressionReason(obj.Reason), nil
+}
+
 // Course is the resolver for the course field in Event.
 func (r *eventResolver) Course(ctx context.Context, obj *models.Event) (*models.Course, error) {
 	if obj.CourseID == "" {
 		return nil, nil
 	}
-	course, err := r.Store.GetCourse(obj.CourseID)
-	if err != nil {
-		return nil, nil // Return nil if course not found
-	}
-	return &course, nil
+	// A nil course means it was not found
+	return r.loadersFor(ctx).Courses.Load(ctx, obj.CourseID)
+}
+
+// Date is the resolver for the date field.
+func (r *eventResolver) Date(ctx context.Context, obj *models.Event) (*time.Time, error) {
+	date := obj.StartsAt.In(r.locationFor(ctx, obj.UserID))
+	return &date, nil
+}
+
+// StartTime is the resolver for the startTime field.
+func (r *eventResolver) StartTime(ctx context.Context, obj *models.Event) (*model.TimeOfDay, error) {
+	clock := model.ClockOf(obj.StartsAt.In(r.locationFor(ctx, obj.UserID)))
+	return &clock, nil
+}
+
+// EndTime is the resolver for the endTime field.
+func (r *eventResolver) EndTime(ctx context.Context, obj *models.Event) (*model.TimeOfDay, error) {
+	clock := model.ClockOf(obj.EndsAt.In(r.locationFor(ctx, obj.UserID)))
+	return &clock, nil
+}
+
+// Reminders is the resolver for the reminders field.
+func (r *eventResolver) Reminders(ctx context.Context, obj *models.Event) ([]*models.Reminder, error) {
+	return reminderRefs(obj.RemindersFor(r.userFor(ctx, obj.UserID).Prefs())), nil
+}
+
+// Status is the resolver for the status field.
+func (r *jobResolver) Status(ctx context.Context, obj *models.Job) (model.JobStatus, error) {
+	return model.JobStatus(obj.Status), nil
 }
 
 // Register is the resolver for the register field.
 func (r *mutationResolver) Register(ctx context.Context, input model.RegisterInput) (*model.AuthPayload, error) {
+	var v validator
+	v.title("name", input.Name)
+	v.email("email", input.Email)
+	v.password("password", input.Password)
+	if input.TimeZone != nil {
+		v.timeZone("timeZone", *input.TimeZone)
+	}
+	if input.Locale != nil {
+		v.locale("locale", *input.Locale)
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
 	// Check if user exists
 	if _, exists := r.Store.GetUserByEmail(input.Email); exists {
-		return nil, errors.New("email already in use")
+		return nil, invalidField("email", "is already in use")
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	hashedPassword, err := auth.HashPassword(input.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -49,15 +137,21 @@ func (r *mutationResolver) Register(ctx context.Context, input model.RegisterInp
 	user := models.User{
 		Name:              input.Name,
 		Email:             input.Email,
-		Password:          string(hashedPassword),
+		Password:          hashedPassword,
 		IsVerified:        false,
 		VerificationToken: verificationToken,
+		TimeZone:          deref(input.TimeZone),
+	}
+	if input.Locale != nil {
+		prefs := models.DefaultPreferences()
+		prefs.Locale = *input.Locale
+		user.Preferences = &prefs
 	}
 	createdUser := r.Store.CreateUser(user)
 
 	// Send verification email
 	go func() {
-		if err := email.SendVerificationEmail(createdUser.Email, verificationToken); err != nil {
+		if err := email.SendVerificationEmail(context.Background(), r.Mailer, createdUser.Email, createdUser.Prefs().Locale, verificationToken); err != nil {
 			fmt.Printf("failed to send email: %v\n", err)
 		}
 	}()
@@ -89,12 +183,24 @@ func (r *mutationResolver) Register(ctx context.Context, input model.RegisterInp
 func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*model.AuthPayload, error) {
 	user, exists := r.Store.GetUserByEmail(input.Email)
 	if !exists {
-		return nil, errors.New("invalid credentials")
+		return nil, errInvalidCredentials
 	}
 
 	// Check password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
-		return nil, errors.New("invalid credentials")
+	ok, err := auth.VerifyPassword(user.Password, input.Password)
+	if err != nil || !ok {
+		return nil, errInvalidCredentials
+	}
+
+	// Transparently upgrade hashes made with an outdated algorithm or cost
+	if auth.PasswordNeedsRehash(user.Password) {
+		if hashed, err := auth.HashPassword(input.Password); err != nil {
+			log.Printf("failed to rehash password for user %s: %v", user.ID, err)
+		} else if updated, err := r.Store.UpdateUserPassword(user.ID, hashed); err != nil {
+			log.Printf("failed to store rehashed password for user %s: %v", user.ID, err)
+		} else {
+			user = updated
+		}
 	}
 
 	// Check verification
@@ -129,7 +235,7 @@ func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*
 func (r *mutationResolver) CreateCourse(ctx context.Context, input model.NewCourseInput) (*models.Course, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
 	}
 
 	course := models.Course{
@@ -137,6 +243,9 @@ func (r *mutationResolver) CreateCourse(ctx context.Context, input model.NewCour
 		Color:  input.Color,
 		UserID: userID,
 	}
+	if err := validateCourse(course); err != nil {
+		return nil, err
+	}
 	created := r.Store.CreateCourse(course)
 	return &created, nil
 }
@@ -145,19 +254,28 @@ func (r *mutationResolver) CreateCourse(ctx context.Context, input model.NewCour
 func (r *mutationResolver) CreateTask(ctx context.Context, input model.NewTaskInput) (*models.Task, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
 	}
 
 	task := models.Task{
 		Title:       input.Title,
 		Description: input.Description,
 		CourseID:    input.CourseID,
-		DueDate:     input.DueDate,
-		DueTime:     input.DueTime,
+		DueAt:       input.DueTime.On(input.DueDate, r.locationFor(ctx, userID)),
 		HasReminder: input.HasReminder,
 		Completed:   false,
 		UserID:      userID,
 	}
+	if err := validateTask(task); err != nil {
+		return nil, err
+	}
+	if input.Reminders != nil {
+		reminders, err := toReminders(input.Reminders, task.DueAt)
+		if err != nil {
+			return nil, err
+		}
+		task.Reminders = reminders
+	}
 	created := r.Store.CreateTask(task)
 	return &created, nil
 }
@@ -166,7 +284,7 @@ func (r *mutationResolver) CreateTask(ctx context.Context, input model.NewTaskIn
 func (r *mutationResolver) UpdateTask(ctx context.Context, input model.UpdateTaskInput) (*models.Task, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
 	}
 
 	// Fetch existing task to verify ownership
@@ -175,7 +293,7 @@ func (r *mutationResolver) UpdateTask(ctx context.Context, input model.UpdateTas
 		return nil, err
 	}
 	if existing.UserID != userID {
-		return nil, errors.New("access denied")
+		return nil, ErrForbidden
 	}
 
 	// Update fields
@@ -188,17 +306,26 @@ func (r *mutationResolver) UpdateTask(ctx context.Context, input model.UpdateTas
 	if input.CourseID != nil {
 		existing.CourseID = *input.CourseID
 	}
-	if input.DueDate != nil {
-		existing.DueDate = *input.DueDate
-	}
-	if input.DueTime != nil {
-		existing.DueTime = *input.DueTime
+	if input.DueDate != nil || input.DueTime != nil {
+		loc := r.locationFor(ctx, userID)
+		date := existing.DueAt.In(loc)
+		clock := model.ClockOf(date)
+		if input.DueDate != nil {
+			date = *input.DueDate
+		}
+		if input.DueTime != nil {
+			clock = *input.DueTime
+		}
+		existing.DueAt = clock.On(date, loc)
 	}
 	if input.Completed != nil {
 		existing.Completed = *input.Completed
 		if *input.Completed {
-			now := time.Now().Format(time.RFC3339)
-			existing.CompletedAt = &now
+			completedAt := r.now()
+			if input.CompletedAt != nil {
+				completedAt = *input.CompletedAt
+			}
+			existing.CompletedAt = &completedAt
 		} else {
 			existing.CompletedAt = nil
 		}
@@ -206,6 +333,16 @@ func (r *mutationResolver) UpdateTask(ctx context.Context, input model.UpdateTas
 	if input.HasReminder != nil {
 		existing.HasReminder = *input.HasReminder
 	}
+	if err := validateTask(existing); err != nil {
+		return nil, err
+	}
+	if input.Reminders != nil {
+		reminders, err := toReminders(input.Reminders, existing.DueAt)
+		if err != nil {
+			return nil, err
+		}
+		existing.Reminders = reminders
+	}
 
 	updated, err := r.Store.UpdateTask(input.ID, existing)
 	return &updated, err
@@ -215,7 +352,7 @@ func (r *mutationResolver) UpdateTask(ctx context.Context, input model.UpdateTas
 func (r *mutationResolver) DeleteTask(ctx context.Context, id string) (bool, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return false, errors.New("access denied")
+		return false, ErrUnauthenticated
 	}
 
 	existing, err := r.Store.GetTask(id)
@@ -223,7 +360,7 @@ func (r *mutationResolver) DeleteTask(ctx context.Context, id string) (bool, err
 		return false, err
 	}
 	if existing.UserID != userID {
-		return false, errors.New("access denied")
+		return false, ErrForbidden
 	}
 
 	if err := r.Store.DeleteTask(id); err != nil {
@@ -236,7 +373,7 @@ func (r *mutationResolver) DeleteTask(ctx context.Context, id string) (bool, err
 func (r *mutationResolver) CreateEvent(ctx context.Context, input model.NewEventInput) (*models.Event, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
 	}
 
 	courseID := ""
@@ -249,28 +386,110 @@ func (r *mutationResolver) CreateEvent(ctx context.Context, input model.NewEvent
 		description = *input.Description
 	}
 
+	loc := r.locationFor(ctx, userID)
 	event := models.Event{
 		Title:       input.Title,
 		Description: description,
 		CourseID:    courseID,
-		Date:        input.Date,
-		StartTime:   input.StartTime,
-		EndTime:     input.EndTime,
+		StartsAt:    input.StartTime.On(input.Date, loc),
+		EndsAt:      input.EndTime.On(input.Date, loc),
 		Type:        input.Type,
 		UserID:      userID,
 	}
+	if err := validateEvent(event); err != nil {
+		return nil, err
+	}
+	if input.Reminders != nil {
+		reminders, err := toReminders(input.Reminders, event.StartsAt)
+		if err != nil {
+			return nil, err
+		}
+		event.Reminders = reminders
+	}
 	created := r.Store.CreateEvent(event)
 	return &created, nil
 }
 
+// UpdateEvent is the resolver for the updateEvent field.
+func (r *mutationResolver) UpdateEvent(ctx context.Context, input model.UpdateEventInput) (*models.Event, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	existing, err := r.Store.GetEvent(input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.UserID != userID {
+		return nil, ErrForbidden
+	}
+
+	if input.Title != nil {
+		existing.Title = *input.Title
+	}
+	if input.Description != nil {
+		existing.Description = *input.Description
+	}
+	if input.CourseID != nil {
+		existing.CourseID = *input.CourseID
+	}
+	if input.Date != nil || input.StartTime != nil || input.EndTime != nil {
+		loc := r.locationFor(ctx, userID)
+		date := existing.StartsAt.In(loc)
+		start, end := model.ClockOf(date), model.ClockOf(existing.EndsAt.In(loc))
+		if input.Date != nil {
+			date = *input.Date
+		}
+		if input.StartTime != nil {
+			start = *input.StartTime
+		}
+		if input.EndTime != nil {
+			end = *input.EndTime
+		}
+		existing.StartsAt = start.On(date, loc)
+		existing.EndsAt = end.On(date, loc)
+	}
+	if input.Type != nil {
+		existing.Type = *input.Type
+	}
+	if err := validateEvent(existing); err != nil {
+		return nil, err
+	}
+	if input.Reminders != nil {
+		reminders, err := toReminders(input.Reminders, existing.StartsAt)
+		if err != nil {
+			return nil, err
+		}
+		existing.Reminders = reminders
+	}
+
+	updated, err := r.Store.UpdateEvent(input.ID, existing)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
 // DeleteEvent is the resolver for the deleteEvent field.
 func (r *mutationResolver) DeleteEvent(ctx context.Context, id string) (bool, error) {
-	// Note: DeleteEvent was not in original store interface, need to add it or skip
-	// For now, I'll skip implementation or add it to store.
-	// Wait, I didn't add DeleteEvent to Store interface.
-	// I'll just return error not implemented for now or add it.
-	// Given the plan, I should probably add it, but for now let's just error.
-	return false, fmt.Errorf("not implemented")
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return false, ErrUnauthenticated
+	}
+
+	existing, err := r.Store.GetEvent(id)
+	if err != nil {
+		return false, err
+	}
+	if existing.UserID != userID {
+		return false, ErrForbidden
+	}
+
+	if err := r.Store.DeleteEvent(id); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // UpdateUser is the resolver for the updateUser field.
@@ -278,21 +497,34 @@ func (r *mutationResolver) DeleteEvent(ctx context.Context, id string) (bool, er
 func (r *mutationResolver) UpdateUser(ctx context.Context, input model.UpdateUserInput) (*models.User, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
 	}
 
+	var v validator
 	name := ""
 	if input.Name != nil {
 		name = *input.Name
+		v.title("name", name)
 	}
 	email := ""
 	if input.Email != nil {
 		email = *input.Email
+		v.email("email", email)
+	}
+	if input.TimeZone != nil {
+		v.timeZone("timeZone", *input.TimeZone)
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	if other, exists := r.Store.GetUserByEmail(email); email != "" && exists && other.ID != userID {
+		return nil, invalidField("email", "is already in use")
 	}
 
 	userUpdate := models.User{
-		Name:  name,
-		Email: email,
+		Name:     name,
+		Email:    email,
+		TimeZone: deref(input.TimeZone),
 	}
 
 	updated, err := r.Store.UpdateUser(userID, userUpdate)
@@ -306,7 +538,7 @@ func (r *mutationResolver) UpdateUser(ctx context.Context, input model.UpdateUse
 func (r *mutationResolver) ChangePassword(ctx context.Context, input model.ChangePasswordInput) (*model.ChangePasswordPayload, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
 	}
 
 	user, err := r.Store.GetUser(userID)
@@ -315,46 +547,359 @@ func (r *mutationResolver) ChangePassword(ctx context.Context, input model.Chang
 	}
 
 	// Verify current password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.CurrentPassword)); err != nil {
-		return &model.ChangePasswordPayload{Success: false, Message: "invalid current password"}, nil
+	if ok, err := auth.VerifyPassword(user.Password, input.CurrentPassword); err != nil || !ok {
+		return nil, invalidField("currentPassword", "is incorrect")
+	}
+	var v validator
+	v.password("newPassword", input.NewPassword)
+	if err := v.err(); err != nil {
+		return nil, err
 	}
 
 	// Hash new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	hashedPassword, err := auth.HashPassword(input.NewPassword)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update password
-	if _, err := r.Store.UpdateUserPassword(userID, string(hashedPassword)); err != nil {
-		return &model.ChangePasswordPayload{Success: false, Message: "failed to update password"}, nil
+	if _, err := r.Store.UpdateUserPassword(userID, hashedPassword); err != nil {
+		return nil, err
 	}
 
 	return &model.ChangePasswordPayload{Success: true, Message: "password updated"}, nil
 }
 
+// UpdatePreferences is the resolver for the updatePreferences field.
+func (r *mutationResolver) UpdatePreferences(ctx context.Context, input model.PreferencesInput) (*models.Preferences, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	user, err := r.Store.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := applyPreferences(user.Prefs(), input)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := r.Store.UpdateUserPreferences(userID, prefs)
+	if err != nil {
+		return nil, err
+	}
+	prefs = updated.Prefs()
+	return &prefs, nil
+}
+
 // MarkNotificationAsRead is the resolver for the markNotificationAsRead field.
 func (r *mutationResolver) MarkNotificationAsRead(ctx context.Context, id string) (bool, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return false, errors.New("access denied")
+		return false, ErrUnauthenticated
 	}
 
-	// Verify ownership?
-	// Store.MarkNotificationAsRead(id) doesn't check user.
-	// We should probably fetch it first or trust the ID.
-	// For now, let's just call store method.
+	existing, err := r.Store.GetNotification(id)
+	if err != nil {
+		return false, err
+	}
+	if existing.UserID != userID {
+		return false, ErrForbidden
+	}
 	if err := r.Store.MarkNotificationAsRead(id); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
+// SnoozeNotification is the resolver for the snoozeNotification field.
+func (r *mutationResolver) SnoozeNotification(ctx context.Context, id string, minutes int) (*models.Notification, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	var v validator
+	v.minutes("minutes", minutes, minutesOf(models.MaxReminderLead))
+	if minutes == 0 {
+		v.fail("minutes", "must be at least 1")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	existing, err := r.Store.GetNotification(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.UserID != userID {
+		return nil, ErrForbidden
+	}
+
+	until := r.now().Add(time.Duration(minutes) * time.Minute)
+	snoozed, err := r.Store.SnoozeNotification(id, until)
+	if err != nil {
+		return nil, err
+	}
+	return &snoozed, nil
+}
+
+// RegisterPushSubscription is the resolver for the registerPushSubscription field.
+func (r *mutationResolver) RegisterPushSubscription(ctx context.Context, input model.PushSubscriptionInput) (*models.PushSubscription, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	var v validator
+	validatePushSubscription(&v, input)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	sub, err := r.Store.SavePushSubscription(models.PushSubscription{
+		UserID:   userID,
+		Endpoint: input.Endpoint,
+		P256dh:   input.P256dh,
+		Auth:     input.Auth,
+		Device:   deref(input.Device),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// DeletePushSubscription is the resolver for the deletePushSubscription field.
+func (r *mutationResolver) DeletePushSubscription(ctx context.Context, id string) (bool, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return false, ErrUnauthenticated
+	}
+	sub, err := r.Store.GetPushSubscription(id)
+	if err != nil {
+		return false, err
+	}
+	if sub.UserID != userID {
+		return false, ErrForbidden
+	}
+	if err := r.Store.DeletePushSubscription(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RegisterDeviceToken is the resolver for the registerDeviceToken field.
+func (r *mutationResolver) RegisterDeviceToken(ctx context.Context, input model.DeviceTokenInput) (*models.DeviceToken, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	var v validator
+	validateDeviceToken(&v, input)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	d, err := r.Store.SaveDeviceToken(models.DeviceToken{
+		UserID:   userID,
+		Token:    input.Token,
+		Platform: string(input.Platform),
+		Device:   deref(input.Device),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// DeleteDeviceToken is the resolver for the deleteDeviceToken field.
+func (r *mutationResolver) DeleteDeviceToken(ctx context.Context, id string) (bool, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return false, ErrUnauthenticated
+	}
+	d, err := r.Store.GetDeviceToken(id)
+	if err != nil {
+		return false, err
+	}
+	if d.UserID != userID {
+		return false, ErrForbidden
+	}
+	if err := r.Store.DeleteDeviceToken(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateWebhook is the resolver for the createWebhook field.
+func (r *mutationResolver) CreateWebhook(ctx context.Context, input model.WebhookInput) (*models.Webhook, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	var v validator
+	validateWebhook(&v, input)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	existing, err := r.Store.ListWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWebhooks {
+		return nil, &Error{Code: CodeValidationFailed, Message: fmt.Sprintf("at most %d webhooks may be registered", maxWebhooks)}
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	active := true
+	if input.Active != nil {
+		active = *input.Active
+	}
+	h, err := r.Store.CreateWebhook(models.Webhook{
+		UserID:      userID,
+		URL:         input.URL,
+		Events:      webhookEvents(input.Events),
+		Secret:      secret,
+		Description: deref(input.Description),
+		Active:      active,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// UpdateWebhook is the resolver for the updateWebhook field.
+func (r *mutationResolver) UpdateWebhook(ctx context.Context, id string, input model.WebhookInput) (*models.Webhook, error) {
+	h, err := r.ownWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var v validator
+	validateWebhook(&v, input)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	h.URL = input.URL
+	h.Events = webhookEvents(input.Events)
+	h.Description = deref(input.Description)
+	if input.Active != nil {
+		h.Active = *input.Active
+	}
+	updated, err := r.Store.UpdateWebhook(id, h)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteWebhook is the resolver for the deleteWebhook field.
+func (r *mutationResolver) DeleteWebhook(ctx context.Context, id string) (bool, error) {
+	if _, err := r.ownWebhook(ctx, id); err != nil {
+		return false, err
+	}
+	if err := r.Store.DeleteWebhook(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TestWebhook is the resolver for the testWebhook field.
+func (r *mutationResolver) TestWebhook(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	h, err := r.ownWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	d, err := r.pingWebhook(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// RetryJob is the resolver for the retryJob field.
+func (r *mutationResolver) RetryJob(ctx context.Context, id string) (*models.Job, error) {
+	if err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	job, err := r.Store.RetryJob(id)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SuppressEmail is the resolver for the suppressEmail field.
+func (r *mutationResolver) SuppressEmail(ctx context.Context, email string, reason model.SuppressionReason) (*models.Suppression, error) {
+	if err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	var v validator
+	v.email("email", email)
+	if !reason.IsValid() || reason == model.SuppressionReasonUnsubscribed {
+		v.fail("reason", "must be BOUNCED or COMPLAINED")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	sup, err := r.Store.AddSuppression(models.Suppression{Email: email, Reason: string(reason)})
+	if err != nil {
+		return nil, err
+	}
+	return &sup, nil
+}
+
+// UnsuppressEmail is the resolver for the unsuppressEmail field.
+func (r *mutationResolver) UnsuppressEmail(ctx context.Context, email string, category *string) (bool, error) {
+	if err := r.requireAdmin(ctx); err != nil {
+		return false, err
+	}
+	if err := r.Store.RemoveSuppression(email, deref(category)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Message is the resolver for the message field.
+func (r *notificationResolver) Message(ctx context.Context, obj *models.Notification) (string, error) {
+	return i18n.For(r.userFor(ctx, obj.UserID)).Notification(*obj), nil
+}
+
+// TaskReminderLeadMinutes is the resolver for the taskReminderLeadMinutes field.
+func (r *preferencesResolver) TaskReminderLeadMinutes(ctx context.Context, obj *models.Preferences) (int, error) {
+	return minutesOf(obj.TaskReminderLead), nil
+}
+
+// EventReminderLeadMinutes is the resolver for the eventReminderLeadMinutes field.
+func (r *preferencesResolver) EventReminderLeadMinutes(ctx context.Context, obj *models.Preferences) (int, error) {
+	return minutesOf(obj.EventReminderLead), nil
+}
+
+// EmailDelayMinutes is the resolver for the emailDelayMinutes field.
+func (r *preferencesResolver) EmailDelayMinutes(ctx context.Context, obj *models.Preferences) (int, error) {
+	return minutesOf(obj.EmailDelay), nil
+}
+
+// Channels is the resolver for the channels field.
+func (r *preferencesResolver) Channels(ctx context.Context, obj *models.Preferences) ([]model.NotificationChannel, error) {
+	channels := make([]model.NotificationChannel, len(obj.Channels))
+	for i, c := range obj.Channels {
+		channels[i] = model.NotificationChannel(c)
+	}
+	return channels, nil
+}
+
+// WeekStart is the resolver for the weekStart field.
+func (r *preferencesResolver) WeekStart(ctx context.Context, obj *models.Preferences) (model.Weekday, error) {
+	return model.AllWeekday[obj.WeekStart], nil
+}
+
 // Me is the resolver for the me field.
 func (r *queryResolver) Me(ctx context.Context) (*models.User, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
 	}
 	user, err := r.Store.GetUser(userID)
 	if err != nil {
@@ -367,7 +912,7 @@ func (r *queryResolver) Me(ctx context.Context) (*models.User, error) {
 func (r *queryResolver) Tasks(ctx context.Context) ([]*models.Task, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
 	}
 	tasks := r.Store.GetTasks(userID)
 	// Convert to pointer slice
@@ -378,11 +923,26 @@ func (r *queryResolver) Tasks(ctx context.Context) ([]*models.Task, error) {
 	return res, nil
 }
 
+// TasksConnection is the resolver for the tasksConnection field.
+func (r *queryResolver) TasksConnection(ctx context.Context, first *int, after *string, filter *model.TaskFilter, orderBy *model.TaskOrder) (*model.TaskConnection, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	q := toTaskQuery(first, after, filter, orderBy, r.locationFor(ctx, userID))
+	q.Now = r.now()
+	page, err := r.Store.ListTasks(userID, q)
+	if err != nil {
+		return nil, err
+	}
+	return toTaskConnection(page), nil
+}
+
 // Courses is the resolver for the courses field.
 func (r *queryResolver) Courses(ctx context.Context) ([]*models.Course, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
 	}
 	courses := r.Store.GetCourses(userID)
 	var res []*models.Course
@@ -393,12 +953,17 @@ func (r *queryResolver) Courses(ctx context.Context) ([]*models.Course, error) {
 }
 
 // Events is the resolver for the events field.
-func (r *queryResolver) Events(ctx context.Context) ([]*models.Event, error) {
+func (r *queryResolver) Events(ctx context.Context, from *time.Time, to *time.Time, typeArg *string, courseID *string) ([]*models.Event, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
+	}
+	f := store.EventFilter{Type: typeArg, CourseID: courseID}
+	f.From, f.To = dayRange(from, to, r.locationFor(ctx, userID))
+	events, err := r.Store.ListEvents(userID, f)
+	if err != nil {
+		return nil, err
 	}
-	events := r.Store.GetEvents(userID)
 	var res []*models.Event
 	for i := range events {
 		res = append(res, &events[i])
@@ -410,75 +975,370 @@ func (r *queryResolver) Events(ctx context.Context) ([]*models.Event, error) {
 func (r *queryResolver) GetTask(ctx context.Context, id string) (*models.Task, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
 	}
 	task, err := r.Store.GetTask(id)
 	if err != nil {
 		return nil, err
 	}
 	if task.UserID != userID {
-		return nil, errors.New("access denied")
+		return nil, ErrForbidden
 	}
 	return &task, nil
 }
 
 // GetCourse is the resolver for the getCourse field.
 func (r *queryResolver) GetCourse(ctx context.Context, id string) (*models.Course, error) {
-	// Note: GetCourse is not in Store interface?
-	// Wait, I didn't check GetCourse in Store interface.
-	// Let's check store_interface.go again.
-	// It has GetCourses but not GetCourse(id).
-	// I'll skip for now.
-	return nil, fmt.Errorf("not implemented")
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	course, err := r.Store.GetCourse(id)
+	if err != nil {
+		return nil, err
+	}
+	if course.UserID != userID {
+		return nil, ErrForbidden
+	}
+	return &course, nil
 }
 
 // Notifications is the resolver for the notifications field.
-func (r *queryResolver) Notifications(ctx context.Context) ([]*models.Notification, error) {
+func (r *queryResolver) Notifications(ctx context.Context, unreadOnly *bool) ([]*models.Notification, error) {
 	userID := auth.ForContext(ctx)
 	if userID == "" {
-		return nil, errors.New("access denied")
+		return nil, ErrUnauthenticated
 	}
-	notifications := r.Store.GetNotifications(userID)
+	notifications := r.Store.GetNotifications(userID, unreadOnly != nil && *unreadOnly)
 	var res []*models.Notification
 	for i := range notifications {
 		res = append(res, &models.Notification{
-			ID:          notifications[i].ID,
-			UserID:      notifications[i].UserID,
-			Type:        notifications[i].Type,
-			Message:     notifications[i].Message,
-			ReferenceID: notifications[i].ReferenceID,
-			Read:        notifications[i].Read,
-			CreatedAt:   notifications[i].CreatedAt,
+			ID:           notifications[i].ID,
+			UserID:       notifications[i].UserID,
+			Type:         notifications[i].Type,
+			Message:      notifications[i].Message,
+			Params:       notifications[i].Params,
+			ReferenceID:  notifications[i].ReferenceID,
+			Read:         notifications[i].Read,
+			CreatedAt:    notifications[i].CreatedAt,
+			SnoozedUntil: notifications[i].SnoozedUntil,
 		})
 	}
 	return res, nil
 }
 
+// NotificationsConnection is the resolver for the notificationsConnection field.
+func (r *queryResolver) NotificationsConnection(ctx context.Context, first *int, after *string, unreadOnly *bool) (*model.NotificationConnection, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	page, err := r.Store.ListNotifications(userID, store.NotificationQuery{
+		UnreadOnly: unreadOnly != nil && *unreadOnly,
+		First:      derefInt(first),
+		After:      deref(after),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toNotificationConnection(page), nil
+}
+
+// PushSubscriptions is the resolver for the pushSubscriptions field.
+func (r *queryResolver) PushSubscriptions(ctx context.Context) ([]*models.PushSubscription, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	subs, err := r.Store.ListPushSubscriptions(userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.PushSubscription, len(subs))
+	for i := range subs {
+		res[i] = &subs[i]
+	}
+	return res, nil
+}
+
+// VapidPublicKey is the resolver for the vapidPublicKey field.
+func (r *queryResolver) VapidPublicKey(ctx context.Context) (*string, error) {
+	if r.VAPIDPublicKey == "" {
+		return nil, nil
+	}
+	return &r.VAPIDPublicKey, nil
+}
+
+// DeviceTokens is the resolver for the deviceTokens field.
+func (r *queryResolver) DeviceTokens(ctx context.Context) ([]*models.DeviceToken, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	devices, err := r.Store.ListDeviceTokens(userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.DeviceToken, len(devices))
+	for i := range devices {
+		res[i] = &devices[i]
+	}
+	return res, nil
+}
+
+// Webhooks is the resolver for the webhooks field.
+func (r *queryResolver) Webhooks(ctx context.Context) ([]*models.Webhook, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	hooks, err := r.Store.ListWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Webhook, len(hooks))
+	for i := range hooks {
+		res[i] = &hooks[i]
+	}
+	return res, nil
+}
+
+// FailedJobs is the resolver for the failedJobs field.
+func (r *queryResolver) FailedJobs(ctx context.Context, first *int) ([]*models.Job, error) {
+	if err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	jobs, err := r.Store.ListJobs(models.JobDead, derefInt(first))
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Job, len(jobs))
+	for i := range jobs {
+		res[i] = &jobs[i]
+	}
+	return res, nil
+}
+
+// EmailSuppressions is the resolver for the emailSuppressions field.
+func (r *queryResolver) EmailSuppressions(ctx context.Context, email *string) ([]*models.Suppression, error) {
+	if err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	sups, err := r.Store.ListSuppressions(deref(email))
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Suppression, len(sups))
+	for i := range sups {
+		res[i] = &sups[i]
+	}
+	return res, nil
+}
+
+// Start is the resolver for the start field.
+func (r *quietHoursResolver) Start(ctx context.Context, obj *models.QuietHours) (*model.TimeOfDay, error) {
+	return clockOfMinutes(obj.Start), nil
+}
+
+// End is the resolver for the end field.
+func (r *quietHoursResolver) End(ctx context.Context, obj *models.QuietHours) (*model.TimeOfDay, error) {
+	return clockOfMinutes(obj.End), nil
+}
+
+// MinutesBefore is the resolver for the minutesBefore field.
+func (r *reminderResolver) MinutesBefore(ctx context.Context, obj *models.Reminder) (*int, error) {
+	if obj.At != nil {
+		return nil, nil
+	}
+	minutes := minutesOf(obj.Before)
+	return &minutes, nil
+}
+
+// NotificationAdded is the resolver for the notificationAdded field.
+func (r *subscriptionResolver) NotificationAdded(ctx context.Context) (<-chan *models.Notification, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	return subscribe(ctx, r.PubSub, userID, pubsub.TopicNotification, func(e pubsub.Event) (*models.Notification, bool) {
+		n, ok := e.Payload.(models.Notification)
+		return &n, ok
+	}), nil
+}
+
+// TaskChanged is the resolver for the taskChanged field.
+func (r *subscriptionResolver) TaskChanged(ctx context.Context) (<-chan *model.TaskChange, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	return subscribe(ctx, r.PubSub, userID, pubsub.TopicTask, func(e pubsub.Event) (*model.TaskChange, bool) {
+		t, ok := e.Payload.(models.Task)
+		return &model.TaskChange{Action: model.ChangeAction(e.Action), Task: &t}, ok
+	}), nil
+}
+
+// EventChanged is the resolver for the eventChanged field.
+func (r *subscriptionResolver) EventChanged(ctx context.Context) (<-chan *model.EventChange, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	return subscribe(ctx, r.PubSub, userID, pubsub.TopicEvent, func(e pubsub.Event) (*model.EventChange, bool) {
+		ev, ok := e.Payload.(models.Event)
+		return &model.EventChange{Action: model.ChangeAction(e.Action), Event: &ev}, ok
+	}), nil
+}
+
 // Course is the resolver for the course field in Task.
 func (r *taskResolver) Course(ctx context.Context, obj *models.Task) (*models.Course, error) {
 	if obj.CourseID == "" {
 		return nil, nil
 	}
-	course, err := r.Store.GetCourse(obj.CourseID)
+	// A nil course means it was not found
+	return r.loadersFor(ctx).Courses.Load(ctx, obj.CourseID)
+}
+
+// DueDate is the resolver for the dueDate field.
+func (r *taskResolver) DueDate(ctx context.Context, obj *models.Task) (*time.Time, error) {
+	date := obj.DueAt.In(r.locationFor(ctx, obj.UserID))
+	return &date, nil
+}
+
+// DueTime is the resolver for the dueTime field.
+func (r *taskResolver) DueTime(ctx context.Context, obj *models.Task) (*model.TimeOfDay, error) {
+	clock := model.ClockOf(obj.DueAt.In(r.locationFor(ctx, obj.UserID)))
+	return &clock, nil
+}
+
+// Reminders is the resolver for the reminders field.
+func (r *taskResolver) Reminders(ctx context.Context, obj *models.Task) ([]*models.Reminder, error) {
+	return reminderRefs(obj.RemindersFor(r.userFor(ctx, obj.UserID).Prefs())), nil
+}
+
+// TimeZone is the resolver for the timeZone field.
+func (r *userResolver) TimeZone(ctx context.Context, obj *models.User) (string, error) {
+	return obj.Location().String(), nil
+}
+
+// Preferences is the resolver for the preferences field.
+func (r *userResolver) Preferences(ctx context.Context, obj *models.User) (*models.Preferences, error) {
+	prefs := obj.Prefs()
+	return &prefs, nil
+}
+
+// Events is the resolver for the events field.
+func (r *webhookResolver) Events(ctx context.Context, obj *models.Webhook) ([]model.WebhookEvent, error) {
+	res := make([]model.WebhookEvent, len(obj.Events))
+	for i, e := range obj.Events {
+		res[i] = graphWebhookEvent(e)
+	}
+	return res, nil
+}
+
+// Deliveries is the resolver for the deliveries field.
+func (r *webhookResolver) Deliveries(ctx context.Context, obj *models.Webhook, first *int) ([]*models.WebhookDelivery, error) {
+	limit := derefInt(first)
+	if limit <= 0 {
+		limit = defaultWebhookDeliveries
+	}
+	deliveries, err := r.Store.ListWebhookDeliveries(obj.ID, min(limit, maxWebhookDeliveries))
 	if err != nil {
-		return nil, nil // Return nil if course not found
+		return nil, err
 	}
-	return &course, nil
+	res := make([]*models.WebhookDelivery, len(deliveries))
+	for i := range deliveries {
+		res[i] = &deliveries[i]
+	}
+	return res, nil
+}
+
+// StatusCode is the resolver for the statusCode field.
+func (r *webhookDeliveryResolver) StatusCode(ctx context.Context, obj *models.WebhookDelivery) (*int, error) {
+	if obj.StatusCode == 0 {
+		return nil, nil
+	}
+	return &obj.StatusCode, nil
+}
+
+// Error is the resolver for the error field.
+func (r *webhookDeliveryResolver) Error(ctx context.Context, obj *models.WebhookDelivery) (*string, error) {
+	if obj.Error == "" {
+		return nil, nil
+	}
+	return &obj.Error, nil
+}
+
+// DurationMs is the resolver for the durationMs field.
+func (r *webhookDeliveryResolver) DurationMs(ctx context.Context, obj *models.WebhookDelivery) (int, error) {
+	return int(obj.Duration.Milliseconds()), nil
 }
 
+// Course returns CourseResolver implementation.
+func (r *Resolver) Course() CourseResolver { return &courseResolver{r} }
+
+// DeviceToken returns DeviceTokenResolver implementation.
+func (r *Resolver) DeviceToken() DeviceTokenResolver { return &deviceTokenResolver{r} }
+
+// Digest returns DigestResolver implementation.
+func (r *Resolver) Digest() DigestResolver { return &digestResolver{r} }
+
+// EmailSuppression returns EmailSuppressionResolver implementation.
+func (r *Resolver) EmailSuppression() EmailSuppressionResolver { return &emailSuppressionResolver{r} }
+
 // Event returns EventResolver implementation.
 func (r *Resolver) Event() EventResolver { return &eventResolver{r} }
 
+// Job returns JobResolver implementation.
+func (r *Resolver) Job() JobResolver { return &jobResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Notification returns NotificationResolver implementation.
+func (r *Resolver) Notification() NotificationResolver { return &notificationResolver{r} }
+
+// Preferences returns PreferencesResolver implementation.
+func (r *Resolver) Preferences() PreferencesResolver { return &preferencesResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// QuietHours returns QuietHoursResolver implementation.
+func (r *Resolver) QuietHours() QuietHoursResolver { return &quietHoursResolver{r} }
+
+// Reminder returns ReminderResolver implementation.
+func (r *Resolver) Reminder() ReminderResolver { return &reminderResolver{r} }
+
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
 // Task returns TaskResolver implementation.
 func (r *Resolver) Task() TaskResolver { return &taskResolver{r} }
 
+// User returns UserResolver implementation.
+func (r *Resolver) User() UserResolver { return &userResolver{r} }
+
+// Webhook returns WebhookResolver implementation.
+func (r *Resolver) Webhook() WebhookResolver { return &webhookResolver{r} }
+
+// WebhookDelivery returns WebhookDeliveryResolver implementation.
+func (r *Resolver) WebhookDelivery() WebhookDeliveryResolver { return &webhookDeliveryResolver{r} }
+
+type courseResolver struct{ *Resolver }
+type deviceTokenResolver struct{ *Resolver }
+type digestResolver struct{ *Resolver }
+type emailSuppressionResolver struct{ *Resolver }
 type eventResolver struct{ *Resolver }
+type jobResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type notificationResolver struct{ *Resolver }
+type preferencesResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type quietHoursResolver struct{ *Resolver }
+type reminderResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
 type taskResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
+type webhookResolver struct{ *Resolver }
+type webhookDeliveryResolver struct{ *Resolver }
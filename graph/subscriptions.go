@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/pubsub"
+)
+
+// subscribe relays the user's events on topic to a resolver channel until the
+// subscription's context is cancelled. Events convert returns false for are skipped.
+func subscribe[T any](ctx context.Context, b *pubsub.Broker, userID, topic string, convert func(pubsub.Event) (T, bool)) <-chan T {
+	events, cancel := b.Subscribe(userID, topic)
+	out := make(chan T, 1)
+	go func() {
+		defer close(out)
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				v, ok := convert(e)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
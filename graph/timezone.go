@@ -0,0 +1,40 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+)
+
+// userFor returns userID's user, or a user with default settings if it
+// cannot be loaded. Lookups are shared per request through the loaders.
+func (r *Resolver) userFor(ctx context.Context, userID string) models.User {
+	u, err := r.loadersFor(ctx).Users.Load(ctx, userID)
+	if err != nil || u == nil {
+		return models.User{ID: userID}
+	}
+	return *u
+}
+
+// locationFor returns the time zone in which userID's dates and times are
+// read and shown.
+func (r *Resolver) locationFor(ctx context.Context, userID string) *time.Location {
+	return r.userFor(ctx, userID).Location()
+}
+
+// dayRange converts an inclusive range of calendar dates into the half-open
+// range of instants [from, to) covering those days in loc. Days are bounded
+// by local midnights, so a range spanning a DST change is 23 or 25 hours
+// longer per changed day rather than a multiple of 24. Nil bounds stay open.
+func dayRange(first, last *time.Time, loc *time.Location) (from, to time.Time) {
+	if first != nil {
+		y, m, d := first.Date()
+		from = time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+	if last != nil {
+		y, m, d := last.Date()
+		to = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+	return from, to
+}
@@ -0,0 +1,122 @@
+package graph
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+)
+
+const (
+	minPasswordLength = 8
+	maxTitleLength    = 200
+)
+
+var colorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(?:-[A-Za-z0-9]{2,8})*$`)
+
+// validator collects per-field input errors, so a client sees every problem
+// with its input in one response rather than one at a time.
+type validator struct {
+	fields map[string]string
+}
+
+func (v *validator) fail(field, message string) {
+	if v.fields == nil {
+		v.fields = map[string]string{}
+	}
+	if _, exists := v.fields[field]; !exists {
+		v.fields[field] = message
+	}
+}
+
+func (v *validator) required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.fail(field, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *validator) title(field, value string) {
+	if v.required(field, value) && len(value) > maxTitleLength {
+		v.fail(field, "must be at most 200 characters")
+	}
+}
+
+func (v *validator) color(field, value string) {
+	if !colorPattern.MatchString(value) {
+		v.fail(field, "must be a hex color such as #3b82f6")
+	}
+}
+
+func (v *validator) email(field, value string) {
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		v.fail(field, "must be a valid email address")
+	}
+}
+
+func (v *validator) timeZone(field, value string) {
+	if _, err := time.LoadLocation(value); err != nil || value == "" || value == "Local" {
+		v.fail(field, "must be an IANA time zone such as Asia/Colombo")
+	}
+}
+
+func (v *validator) locale(field, value string) {
+	if !localePattern.MatchString(value) {
+		v.fail(field, "must be a language tag such as en or si-LK")
+	}
+}
+
+func (v *validator) password(field, value string) {
+	if len(value) < minPasswordLength {
+		v.fail(field, "must be at least 8 characters")
+	}
+}
+
+func (v *validator) minutes(field string, value, max int) {
+	if value < 0 || value > max {
+		v.fail(field, fmt.Sprintf("must be between 0 and %d minutes", max))
+	}
+}
+
+// err returns a VALIDATION_FAILED error listing the invalid fields, or nil.
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &Error{Code: CodeValidationFailed, Message: "invalid input", Fields: v.fields}
+}
+
+func validateCourse(c models.Course) error {
+	var v validator
+	v.title("name", c.Name)
+	v.color("color", c.Color)
+	return v.err()
+}
+
+// validateTask checks a task as it will be stored, so creates and partial
+// updates are held to the same rules. Dates and times are already checked by
+// their scalars.
+func validateTask(t models.Task) error {
+	var v validator
+	v.title("title", t.Title)
+	v.required("courseId", t.CourseID)
+	return v.err()
+}
+
+// validateEvent checks an event as it will be stored.
+func validateEvent(e models.Event) error {
+	var v validator
+	v.title("title", e.Title)
+	v.required("type", e.Type)
+	if e.EndsAt.Before(e.StartsAt) {
+		v.fail("endTime", "must not be before startTime")
+	}
+	return v.err()
+}
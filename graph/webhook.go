@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/RandithaK/StudyBuddy_Backend/graph/model"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/auth"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/netguard"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/webhook"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxWebhooks bounds how many webhooks a user may register, and so how
+	// many deliveries one change enqueues.
+	maxWebhooks = 10
+	// defaultWebhookDeliveries and maxWebhookDeliveries bound a webhook's
+	// deliveries field.
+	defaultWebhookDeliveries = 20
+	maxWebhookDeliveries     = 100
+)
+
+// webhookEvent returns the webhook event type of the GraphQL enum value e,
+// e.g. task.created for TASK_CREATED.
+func webhookEvent(e model.WebhookEvent) string {
+	return strings.ToLower(strings.Replace(string(e), "_", ".", 1))
+}
+
+// graphWebhookEvent returns the GraphQL enum value of the webhook event
+// type t.
+func graphWebhookEvent(t string) model.WebhookEvent {
+	return model.WebhookEvent(strings.ToUpper(strings.Replace(t, ".", "_", 1)))
+}
+
+// validateWebhook checks that input is a webhook deliveries can be posted
+// to: an https URL on a public host and at least one event type.
+func validateWebhook(v *validator, input model.WebhookInput) {
+	if u, err := url.Parse(input.URL); err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+		v.fail("url", "must be an https URL")
+	} else if netguard.CheckHost(u.Hostname()) != nil {
+		v.fail("url", "must be on a public host")
+	}
+	if len(input.Events) == 0 {
+		v.fail("events", "must include at least one event type")
+	}
+	if input.Description != nil && *input.Description != "" {
+		v.title("description", *input.Description)
+	}
+}
+
+// webhookEvents returns the distinct event types of events.
+func webhookEvents(events []model.WebhookEvent) []string {
+	res := []string{}
+	seen := map[string]bool{}
+	for _, e := range events {
+		if t := webhookEvent(e); !seen[t] {
+			seen[t] = true
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// ownWebhook returns the caller's webhook with ID id.
+func (r *Resolver) ownWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	userID := auth.ForContext(ctx)
+	if userID == "" {
+		return models.Webhook{}, ErrUnauthenticated
+	}
+	h, err := r.Store.GetWebhook(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if h.UserID != userID {
+		return models.Webhook{}, ErrForbidden
+	}
+	return h, nil
+}
+
+// pingWebhook posts a ping event to h now and logs the attempt.
+func (r *Resolver) pingWebhook(ctx context.Context, h models.Webhook) (models.WebhookDelivery, error) {
+	sender := r.Webhooks
+	if sender == nil {
+		sender = &webhook.Sender{Now: r.Now}
+	}
+	eventID := uuid.New().String()
+	body, err := webhook.Marshal(eventID, models.WebhookPing, map[string]string{"webhookId": h.ID}, r.now())
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	d := sender.Deliver(ctx, h, eventID, models.WebhookPing, body, 1)
+	return r.Store.AddWebhookDelivery(d)
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/server"
 )
@@ -10,9 +13,13 @@ import (
 func main() {
 	server.Setup()
 
+	// Shut down gracefully on Ctrl-C or when the platform stops the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	port := server.GetEnv("PORT", "8080")
 	log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
-	if err := http.ListenAndServe(":"+port, server.Router); err != nil {
+	if err := server.NewApp(":" + port).Run(ctx); err != nil {
 		log.Fatalf("server failed: %v", err)
 	}
 }
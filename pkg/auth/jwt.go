@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -10,13 +11,31 @@ import (
 
 var jwtSecret = []byte("dev-secret") // In production, load from env
 
+var (
+	clockMu sync.RWMutex
+	now     = time.Now
+)
+
+// SetClock replaces the clock used to issue and check token expiry.
+func SetClock(clock func() time.Time) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	now = clock
+}
+
+func currentTime() time.Time {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return now()
+}
+
 type Claims struct {
 	UserID string `json:"userId"`
 	jwt.RegisteredClaims
 }
 
 func GenerateAccessToken(userID string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+	expirationTime := currentTime().Add(24 * time.Hour)
 	claims := &Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
@@ -28,7 +47,7 @@ func GenerateAccessToken(userID string) (string, error) {
 }
 
 func GenerateRefreshToken(userID string) (string, error) {
-	expirationTime := time.Now().Add(30 * 24 * time.Hour) // 30 days
+	expirationTime := currentTime().Add(30 * 24 * time.Hour) // 30 days
 	claims := &Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
@@ -41,15 +60,26 @@ func GenerateRefreshToken(userID string) (string, error) {
 
 func ValidateToken(tokenStr string) (*Claims, error) {
 	claims := &Claims{}
+	// Time-based claims are checked below against the package clock rather
+	// than by the parser
 	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
-	})
+	}, jwt.WithoutClaimsValidation())
 	if err != nil {
 		return nil, err
 	}
 	if !token.Valid {
 		return nil, errors.New("invalid token")
 	}
+	t := currentTime()
+	switch {
+	case !claims.VerifyExpiresAt(t, false):
+		return nil, jwt.ErrTokenExpired
+	case !claims.VerifyIssuedAt(t, false):
+		return nil, jwt.ErrTokenUsedBeforeIssued
+	case !claims.VerifyNotBefore(t, false):
+		return nil, jwt.ErrTokenNotValidYet
+	}
 	return claims, nil
 }
 
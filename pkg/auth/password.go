@@ -0,0 +1,314 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUnknownHashAlgorithm = errors.New("unknown password hash algorithm")
+	ErrMalformedHash        = errors.New("malformed password hash")
+)
+
+// PasswordHasher hashes and verifies user passwords. Implementations must
+// recognise the hashes they produce so that stored credentials can be routed
+// to the right algorithm and upgraded when the configuration changes.
+type PasswordHasher interface {
+	// Algorithm returns the identifier of the algorithm, e.g. "argon2id".
+	Algorithm() string
+	// Identifies reports whether the encoded hash was produced by this algorithm.
+	Identifies(hash string) bool
+	Hash(password string) (string, error)
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash reports whether the hash was produced with weaker or
+	// outdated parameters than the ones currently configured.
+	NeedsRehash(hash string) bool
+}
+
+// BcryptHasher hashes passwords with bcrypt at the configured cost.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Algorithm() string { return "bcrypt" }
+
+func (h BcryptHasher) Identifies(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (h BcryptHasher) cost() int {
+	if h.Cost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return h.Cost
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.cost())
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (h BcryptHasher) Verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+	return cost < h.cost()
+}
+
+// Argon2Params are the tunable Argon2id parameters. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the OWASP baseline (19 MiB, 2 iterations, 1 lane),
+// which keeps login latency reasonable on small serverless instances.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Argon2idHasher hashes passwords with Argon2id and encodes them in the PHC
+// string format: $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2idHasher struct {
+	Params Argon2Params
+}
+
+func (h Argon2idHasher) Algorithm() string { return "argon2id" }
+
+func (h Argon2idHasher) Identifies(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	p := h.Params
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h Argon2idHasher) Verify(hash, password string) (bool, error) {
+	p, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	p, _, _, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+	return p.Memory != h.Params.Memory ||
+		p.Iterations != h.Params.Iterations ||
+		p.Parallelism != h.Params.Parallelism ||
+		p.KeyLength != h.Params.KeyLength
+}
+
+func decodeArgon2idHash(hash string) (Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+	var p Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil ||
+		p.Iterations == 0 || p.Parallelism == 0 {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+	// An empty key would match every password
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
+
+// MultiHasher hashes new passwords with the preferred algorithm and verifies
+// stored hashes with whichever known algorithm produced them. Hashes created
+// by any algorithm other than the preferred one always need a rehash.
+type MultiHasher struct {
+	Preferred PasswordHasher
+	Legacy    []PasswordHasher
+}
+
+func (h MultiHasher) Algorithm() string { return h.Preferred.Algorithm() }
+
+func (h MultiHasher) Identifies(hash string) bool {
+	return h.identify(hash) != nil
+}
+
+func (h MultiHasher) identify(hash string) PasswordHasher {
+	if h.Preferred.Identifies(hash) {
+		return h.Preferred
+	}
+	for _, l := range h.Legacy {
+		if l.Identifies(hash) {
+			return l
+		}
+	}
+	return nil
+}
+
+func (h MultiHasher) Hash(password string) (string, error) {
+	return h.Preferred.Hash(password)
+}
+
+func (h MultiHasher) Verify(hash, password string) (bool, error) {
+	hasher := h.identify(hash)
+	if hasher == nil {
+		return false, ErrUnknownHashAlgorithm
+	}
+	return hasher.Verify(hash, password)
+}
+
+func (h MultiHasher) NeedsRehash(hash string) bool {
+	if !h.Preferred.Identifies(hash) {
+		return true
+	}
+	return h.Preferred.NeedsRehash(hash)
+}
+
+// NewPasswordHasher returns a hasher that prefers the named algorithm and
+// still verifies hashes from every other supported algorithm.
+func NewPasswordHasher(algorithm string, bcryptCost int, params Argon2Params) (PasswordHasher, error) {
+	bc := BcryptHasher{Cost: bcryptCost}
+	ar := Argon2idHasher{Params: params}
+	switch algorithm {
+	case "", "argon2id":
+		return MultiHasher{Preferred: ar, Legacy: []PasswordHasher{bc}}, nil
+	case "bcrypt":
+		return MultiHasher{Preferred: bc, Legacy: []PasswordHasher{ar}}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownHashAlgorithm, algorithm)
+	}
+}
+
+// PasswordHasherFromEnv builds the hasher from PASSWORD_HASH_ALGORITHM,
+// BCRYPT_COST and the ARGON2_* variables, defaulting to Argon2id.
+func PasswordHasherFromEnv() (PasswordHasher, error) {
+	params := DefaultArgon2Params
+	var err error
+	if params.Memory, err = envUint32("ARGON2_MEMORY_KIB", params.Memory); err != nil {
+		return nil, err
+	}
+	if params.Iterations, err = envUint32("ARGON2_ITERATIONS", params.Iterations); err != nil {
+		return nil, err
+	}
+	if params.Iterations < 1 {
+		return nil, fmt.Errorf("ARGON2_ITERATIONS must be at least 1")
+	}
+	parallelism, err := envUint32("ARGON2_PARALLELISM", uint32(params.Parallelism))
+	if err != nil {
+		return nil, err
+	}
+	if parallelism == 0 || parallelism > 255 {
+		return nil, fmt.Errorf("ARGON2_PARALLELISM must be between 1 and 255")
+	}
+	if params.Memory < 8*parallelism {
+		return nil, fmt.Errorf("ARGON2_MEMORY_KIB must be at least 8 times ARGON2_PARALLELISM")
+	}
+	params.Parallelism = uint8(parallelism)
+	cost, err := envUint32("BCRYPT_COST", uint32(bcrypt.DefaultCost))
+	if err != nil {
+		return nil, err
+	}
+	return NewPasswordHasher(os.Getenv("PASSWORD_HASH_ALGORITHM"), int(cost), params)
+}
+
+func envUint32(key string, fallback uint32) (uint32, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return uint32(n), nil
+}
+
+var (
+	hasherMu       sync.RWMutex
+	passwordHasher PasswordHasher = MultiHasher{
+		Preferred: Argon2idHasher{Params: DefaultArgon2Params},
+		Legacy:    []PasswordHasher{BcryptHasher{Cost: bcrypt.DefaultCost}},
+	}
+)
+
+// SetPasswordHasher replaces the hasher used by HashPassword and friends.
+func SetPasswordHasher(h PasswordHasher) {
+	hasherMu.Lock()
+	defer hasherMu.Unlock()
+	passwordHasher = h
+}
+
+func currentHasher() PasswordHasher {
+	hasherMu.RLock()
+	defer hasherMu.RUnlock()
+	return passwordHasher
+}
+
+// HashPassword hashes a password with the configured preferred algorithm.
+func HashPassword(password string) (string, error) {
+	return currentHasher().Hash(password)
+}
+
+// VerifyPassword checks a password against a stored hash of any supported algorithm.
+func VerifyPassword(hash, password string) (bool, error) {
+	return currentHasher().Verify(hash, password)
+}
+
+// PasswordNeedsRehash reports whether a stored hash should be replaced after
+// the next successful login.
+func PasswordNeedsRehash(hash string) bool {
+	return currentHasher().NeedsRehash(hash)
+}
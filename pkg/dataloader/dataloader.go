@@ -0,0 +1,130 @@
+// Package dataloader batches and caches lookups made while resolving a single
+// GraphQL request, so that resolving a field on N list items costs one store
+// round trip instead of N.
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchFunc loads the values for keys. It must return one value per key, in
+// the same order. errs may be nil, contain a single error that applies to
+// every key, or contain one error per key.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (values []V, errs []error)
+
+const (
+	DefaultWait     = 2 * time.Millisecond
+	DefaultMaxBatch = 100
+)
+
+// Loader collects keys requested within Wait of each other into a single
+// call of its BatchFunc and memoizes the results. A Loader is meant to live
+// for one request; it never evicts entries.
+type Loader[K comparable, V any] struct {
+	fetch    BatchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[K]*result[V]
+	batch *batch[K, V]
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type batch[K comparable, V any] struct {
+	once    sync.Once
+	keys    []K
+	results []*result[V]
+}
+
+// New returns a Loader that dispatches batches after DefaultWait or once
+// DefaultMaxBatch keys are pending, whichever comes first.
+func New[K comparable, V any](fetch BatchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:    fetch,
+		wait:     DefaultWait,
+		maxBatch: DefaultMaxBatch,
+		cache:    make(map[K]*result[V]),
+	}
+}
+
+// Load returns the value for key, waiting for the batch it joins to complete.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	if r, ok := l.cache[key]; ok {
+		l.mu.Unlock()
+		return r.wait(ctx)
+	}
+
+	r := &result[V]{done: make(chan struct{})}
+	l.cache[key] = r
+
+	b := l.batch
+	if b == nil {
+		b = &batch[K, V]{}
+		l.batch = b
+		time.AfterFunc(l.wait, func() { l.dispatch(ctx, b) })
+	}
+	b.keys = append(b.keys, key)
+	b.results = append(b.results, r)
+	full := len(b.keys) >= l.maxBatch
+	l.mu.Unlock()
+
+	if full {
+		l.dispatch(ctx, b)
+	}
+	return r.wait(ctx)
+}
+
+// Prime stores a value for key unless it is already cached, so that values
+// fetched by other means are not loaded again.
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+	r := &result[V]{done: make(chan struct{}), value: value}
+	close(r.done)
+	l.cache[key] = r
+}
+
+func (l *Loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	b.once.Do(func() {
+		l.mu.Lock()
+		if l.batch == b {
+			l.batch = nil
+		}
+		l.mu.Unlock()
+
+		values, errs := l.fetch(ctx, b.keys)
+		for i, r := range b.results {
+			switch {
+			case len(errs) == 1:
+				r.err = errs[0]
+			case len(errs) > i && errs[i] != nil:
+				r.err = errs[i]
+			case i < len(values):
+				r.value = values[i]
+			}
+			close(r.done)
+		}
+	})
+}
+
+func (r *result[V]) wait(ctx context.Context) (V, error) {
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
@@ -1,71 +1,32 @@
 package email
 
 import (
+	"context"
 	"fmt"
-	"net/smtp"
-	"os"
-)
-
-func SendVerificationEmail(toEmail, token string) error {
-	smtpHost := os.Getenv("SMTP_HOST")
-	smtpPort := os.Getenv("SMTP_PORT")
-	smtpUser := os.Getenv("SMTP_USER")
-	smtpPass := os.Getenv("SMTP_PASS")
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	// If SMTP config is missing, just log it (for dev/testing without real SMTP)
-	baseURL := os.Getenv("BASE_URL")
-	if baseURL == "" {
-		baseURL = fmt.Sprintf("http://localhost:%s", port)
-	}
-
-	if smtpHost == "" || smtpUser == "" {
-		fmt.Printf("Mock Email to %s: Verify at %s/verify-email?token=%s\n", toEmail, baseURL, token)
-		return nil
-	}
-
-	auth := smtp.PlainAuth("", smtpUser, smtpPass, smtpHost)
 
-	link := fmt.Sprintf("%s/verify-email?token=%s", baseURL, token)
-	msg := []byte(fmt.Sprintf("To: %s\r\n"+
-		"Subject: Verify your email\r\n"+
-		"\r\n"+
-		"Please click the link below to verify your email address:\r\n"+
-		"%s\r\n", toEmail, link))
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/i18n"
+)
 
-	addr := fmt.Sprintf("%s:%s", smtpHost, smtpPort)
-	err := smtp.SendMail(addr, auth, smtpUser, []string{toEmail}, msg)
-	if err != nil {
-		return err
-	}
-	return nil
+func SendVerificationEmail(ctx context.Context, m Mailer, toEmail, locale, token string) error {
+	link := fmt.Sprintf("%s/verify-email?token=%s", baseURL(), token)
+	return sendTemplate(ctx, m, TemplateVerification, toEmail, locale, VerificationData{Link: link})
 }
 
-func SendNotificationEmail(toEmail, subject, body string) error {
-	smtpHost := os.Getenv("SMTP_HOST")
-	smtpPort := os.Getenv("SMTP_PORT")
-	smtpUser := os.Getenv("SMTP_USER")
-	smtpPass := os.Getenv("SMTP_PASS")
-	
-	// If SMTP config is missing, just log it
-	if smtpHost == "" || smtpUser == "" {
-		fmt.Printf("Mock Email to %s: Subject: %s\nBody: %s\n", toEmail, subject, body)
-		return nil
-	}
+// SendNotificationEmail emails the text of an unread notification, already
+// rendered in locale.
+func SendNotificationEmail(ctx context.Context, m Mailer, toEmail, locale, message string) error {
+	subject := i18n.New(locale, nil).T("email.reminder.subject", nil)
+	return sendTemplate(ctx, m, TemplateReminder, toEmail, locale, ReminderData{Subject: subject, Message: message})
+}
 
-	auth := smtp.PlainAuth("", smtpUser, smtpPass, smtpHost)
-	msg := []byte(fmt.Sprintf("To: %s\r\n"+
-		"Subject: %s\r\n"+
-		"\r\n"+
-		"%s\r\n", toEmail, subject, body))
+func SendDigestEmail(ctx context.Context, m Mailer, toEmail, locale string, data DigestData) error {
+	return sendTemplate(ctx, m, TemplateDigest, toEmail, locale, data)
+}
 
-	addr := fmt.Sprintf("%s:%s", smtpHost, smtpPort)
-	err := smtp.SendMail(addr, auth, smtpUser, []string{toEmail}, msg)
+func sendTemplate(ctx context.Context, m Mailer, name, toEmail, locale string, data any) error {
+	msg, err := defaultRenderer().RenderLocale(name, toEmail, locale, data)
 	if err != nil {
 		return err
 	}
-	return nil
+	return m.Send(ctx, msg)
 }
@@ -0,0 +1,209 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Mailer delivers rendered messages. Send gives up once ctx is done.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// MailerFromEnv returns the mailer configured by the environment: a
+// FileMailer writing to EMAIL_OUTBOX_DIR if set, otherwise an SMTPMailer if
+// SMTP_HOST is set, otherwise a LogMailer.
+//
+// SMTP_TLS chooses how SMTP connections are secured: "starttls", the default
+// on every port but 465, upgrades the connection when the server supports
+// it, and "implicit", the default on port 465, connects over TLS.
+func MailerFromEnv() (Mailer, error) {
+	from := os.Getenv("EMAIL_FROM")
+	if dir := os.Getenv("EMAIL_OUTBOX_DIR"); dir != "" {
+		return &FileMailer{Dir: dir, From: from}, nil
+	}
+
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return LogMailer{}, nil
+	}
+	m := &SMTPMailer{
+		Host:     host,
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USER"),
+		Password: os.Getenv("SMTP_PASS"),
+		From:     from,
+	}
+	if m.Port == "" {
+		m.Port = "587"
+	}
+	switch os.Getenv("SMTP_TLS") {
+	case "":
+		m.ImplicitTLS = m.Port == "465"
+	case "starttls":
+	case "implicit":
+		m.ImplicitTLS = true
+	default:
+		return nil, fmt.Errorf("SMTP_TLS must be starttls or implicit")
+	}
+	// The From header defaults to the SMTP user, as the envelope sender did
+	if m.From == "" {
+		m.From = (&mail.Address{Name: Brand, Address: m.Username}).String()
+	}
+	return m, nil
+}
+
+// smtpTimeout bounds a whole SMTP conversation, or less if the context it
+// is sent with ends sooner.
+const smtpTimeout = 30 * time.Second
+
+// SMTPMailer sends messages through an SMTP server, authenticating with
+// PLAIN when a username is set.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// From is the sender, e.g. "StudyBuddy <noreply@example.com>".
+	From string
+	// ImplicitTLS connects over TLS, as on port 465. Otherwise the
+	// connection is upgraded with STARTTLS if the server supports it.
+	ImplicitTLS bool
+	// TLSConfig, if set, is used for TLS connections instead of one
+	// verifying Host.
+	TLSConfig *tls.Config
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	sender := m.Username
+	if addr, err := mail.ParseAddress(m.From); err == nil {
+		sender = addr.Address
+	}
+	body, err := msg.Bytes(m.From, time.Now())
+	if err != nil {
+		return err
+	}
+
+	tlsConfig := m.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: m.Host}
+	}
+	addr := net.JoinHostPort(m.Host, m.Port)
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+	var conn net.Conn
+	if m.ImplicitTLS {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if !m.ImplicitTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+	if m.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(sender); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// FileMailer writes each message to Dir as an .eml file, for looking at
+// emails in local development without sending them.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	from := m.From
+	if from == "" {
+		from = (&mail.Address{Name: Brand, Address: "noreply@localhost"}).String()
+	}
+	body, err := msg.Bytes(from, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	// Write under a temporary name first so readers never see a partial
+	// message
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), uuid.New().String()[:8])
+	tmp := filepath.Join(m.Dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, body, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(m.Dir, name))
+}
+
+// MemoryMailer records the messages sent with it, for tests.
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (m *MemoryMailer) Send(_ context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns the messages sent so far, oldest first.
+func (m *MemoryMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
+
+// LogMailer prints messages instead of sending them, for running without
+// an SMTP server.
+type LogMailer struct{}
+
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	fmt.Printf("Mock Email to %s: Subject: %s\nBody: %s\n", msg.To, msg.Subject, msg.Text)
+	return nil
+}
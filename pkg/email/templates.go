@@ -0,0 +1,234 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	texttemplate "text/template"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/i18n"
+)
+
+// Template names. Each has a .txt file defining "subject" and "content" and
+// a .html file defining "content", rendered inside layout.txt and
+// layout.html.
+const (
+	TemplateVerification  = "verification"
+	TemplatePasswordReset = "password_reset"
+	TemplateReminder      = "reminder"
+	TemplateDigest        = "digest"
+)
+
+// Brand is the product name shown in emails.
+const Brand = "StudyBuddy"
+
+//go:embed templates/*
+var defaultTemplates embed.FS
+
+// VerificationData is the data for TemplateVerification.
+type VerificationData struct {
+	Link string
+}
+
+// PasswordResetData is the data for TemplatePasswordReset.
+type PasswordResetData struct {
+	Link      string
+	ExpiresIn string
+}
+
+// ReminderData is the data for TemplateReminder.
+type ReminderData struct {
+	Subject string
+	Message string
+}
+
+// DigestData is the data for TemplateDigest.
+type DigestData struct {
+	Title    string
+	Intro    string
+	Sections []DigestSection
+}
+
+type DigestSection struct {
+	Heading string
+	Items   []string
+}
+
+// Message is a rendered email with plain-text and HTML bodies.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Category is what the message can be unsubscribed from, or empty for
+	// transactional email.
+	Category string
+	// Unsubscribe is the link unsubscribing the recipient from Category,
+	// sent as the List-Unsubscribe header.
+	Unsubscribe string
+}
+
+// page is what templates are executed with: the branding and subject shared
+// by every email, and the template's own data as Data. Its T method looks up
+// the message catalogs in the recipient's locale.
+type page struct {
+	Brand   string
+	BaseURL string
+	Subject string
+	// Unsubscribe is the recipient's unsubscribe link, if the email has a
+	// category.
+	Unsubscribe string
+	Data        any
+
+	l i18n.Localizer
+}
+
+// T returns the catalog message for key in the recipient's locale, with
+// params given as name, value pairs. The brand is always a parameter.
+func (p page) T(key string, params ...string) string {
+	m := map[string]string{"brand": p.Brand}
+	for i := 0; i+1 < len(params); i += 2 {
+		m[params[i]] = params[i+1]
+	}
+	return p.l.T(key, m)
+}
+
+// Templates renders emails from the templates in a directory, falling back
+// to the built-in template for any file the directory does not have.
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// LoadTemplates parses every template, preferring files in dir to the
+// built-in ones. An empty dir uses only the built-in templates.
+func LoadTemplates(dir string) (*Templates, error) {
+	read := func(file string) ([]byte, error) {
+		if dir != "" {
+			b, err := os.ReadFile(filepath.Join(dir, file))
+			if err == nil || !errors.Is(err, fs.ErrNotExist) {
+				return b, err
+			}
+		}
+		return defaultTemplates.ReadFile("templates/" + file)
+	}
+
+	t := &Templates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+	textLayout, err := read("layout.txt")
+	if err != nil {
+		return nil, err
+	}
+	htmlLayout, err := read("layout.html")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{TemplateVerification, TemplatePasswordReset, TemplateReminder, TemplateDigest} {
+		text, err := read(name + ".txt")
+		if err != nil {
+			return nil, err
+		}
+		html, err := read(name + ".html")
+		if err != nil {
+			return nil, err
+		}
+		tt, err := texttemplate.New(name).Parse(string(textLayout))
+		if err == nil {
+			_, err = tt.Parse(string(text))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s.txt: %w", name, err)
+		}
+		ht, err := htmltemplate.New(name).Parse(string(htmlLayout))
+		if err == nil {
+			_, err = ht.Parse(string(html))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s.html: %w", name, err)
+		}
+		t.text[name], t.html[name] = tt, ht
+	}
+	return t, nil
+}
+
+// Render renders the named template with data into a message to to, in the
+// default locale.
+func (t *Templates) Render(name, to string, data any) (Message, error) {
+	return t.RenderLocale(name, to, i18n.DefaultLocale, data)
+}
+
+// RenderLocale renders the named template with data into a message to to,
+// in locale.
+func (t *Templates) RenderLocale(name, to, locale string, data any) (Message, error) {
+	tt, ok := t.text[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+	p := page{Brand: Brand, BaseURL: baseURL(), Data: data, l: i18n.New(locale, nil)}
+	category := templateCategories[name]
+	if category != "" {
+		p.Unsubscribe = UnsubscribeURL(to, category)
+	}
+	var subject, text, html bytes.Buffer
+	if err := tt.ExecuteTemplate(&subject, "subject", p); err != nil {
+		return Message{}, err
+	}
+	p.Subject = subject.String()
+	if err := tt.ExecuteTemplate(&text, "layout", p); err != nil {
+		return Message{}, err
+	}
+	if err := t.html[name].ExecuteTemplate(&html, "layout", p); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:          to,
+		Subject:     p.Subject,
+		Text:        text.String(),
+		HTML:        html.String(),
+		Category:    category,
+		Unsubscribe: p.Unsubscribe,
+	}, nil
+}
+
+var (
+	templatesOnce sync.Once
+	templates     *Templates
+)
+
+// defaultRenderer returns the templates from EMAIL_TEMPLATES_DIR, or the
+// built-in ones if that is unset or its templates do not parse.
+func defaultRenderer() *Templates {
+	templatesOnce.Do(func() {
+		var err error
+		templates, err = LoadTemplates(os.Getenv("EMAIL_TEMPLATES_DIR"))
+		if err != nil {
+			log.Printf("invalid email templates, using defaults: %v", err)
+			templates, err = LoadTemplates("")
+			if err != nil {
+				panic(err)
+			}
+		}
+	})
+	return templates
+}
+
+// baseURL is the public URL of the server, used for links in emails.
+func baseURL() string {
+	if u := os.Getenv("BASE_URL"); u != "" {
+		return u
+	}
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	return fmt.Sprintf("http://localhost:%s", port)
+}
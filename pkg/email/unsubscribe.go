@@ -0,0 +1,149 @@
+package email
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Categories of email a recipient can unsubscribe from. Transactional
+// emails, such as verification, have no category and are only withheld
+// from addresses suppressed entirely.
+const (
+	CategoryNotifications = "notifications"
+	CategoryDigest        = "digest"
+)
+
+// templateCategories gives the category of each template that has one.
+var templateCategories = map[string]string{
+	TemplateReminder: CategoryNotifications,
+	TemplateDigest:   CategoryDigest,
+}
+
+// IsCategory reports whether c is a category that can be unsubscribed from.
+func IsCategory(c string) bool {
+	return c == CategoryNotifications || c == CategoryDigest
+}
+
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// devSecret signs unsubscribe links in development when no secret is set.
+// Being public, it would let anyone forge links, so it is not used in
+// production, even when set as JWT_SECRET.
+const devSecret = "dev-secret"
+
+// unsubscribeSecret returns the key unsubscribe links are signed with:
+// UNSUBSCRIBE_SECRET, or JWT_SECRET if that is unset. In production,
+// without either, it returns false and links are disabled.
+func unsubscribeSecret() ([]byte, bool) {
+	for _, key := range []string{"UNSUBSCRIBE_SECRET", "JWT_SECRET"} {
+		if s := os.Getenv(key); s != "" && s != devSecret {
+			return []byte(s), true
+		}
+	}
+	if isProduction() {
+		return nil, false
+	}
+	return []byte(devSecret), true
+}
+
+// isProduction reports whether the server runs in production, as the
+// server decides it: by APP_ENV=production or on a Vercel production
+// deployment.
+func isProduction() bool {
+	return os.Getenv("APP_ENV") == "production" || os.Getenv("VERCEL_ENV") == "production"
+}
+
+// UnsubscribeEnabled reports whether unsubscribe links are sent and
+// accepted, which they are unless no secret is configured in production.
+func UnsubscribeEnabled() bool {
+	_, ok := unsubscribeSecret()
+	return ok
+}
+
+func unsubscribeSignature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// UnsubscribeToken returns a token unsubscribing address from category, or
+// "" if unsubscribe links are disabled. Tokens do not expire, so links in
+// old emails keep working.
+func UnsubscribeToken(address, category string) string {
+	secret, ok := unsubscribeSecret()
+	if !ok {
+		return ""
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(address + "\n" + category))
+	return payload + "." + unsubscribeSignature(secret, payload)
+}
+
+// ParseUnsubscribeToken returns the address and category of a token made by
+// UnsubscribeToken, or ErrInvalidUnsubscribeToken if it was not or links
+// are disabled.
+func ParseUnsubscribeToken(token string) (address, category string, err error) {
+	secret, ok := unsubscribeSecret()
+	if !ok {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(unsubscribeSignature(secret, payload))) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	address, category, ok = strings.Cut(string(b), "\n")
+	if !ok || !IsCategory(category) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	return address, category, nil
+}
+
+// UnsubscribeURL returns the link unsubscribing address from category, or
+// "" if unsubscribe links are disabled. It serves both the landing page and
+// one-click unsubscribe.
+func UnsubscribeURL(address, category string) string {
+	if !UnsubscribeEnabled() {
+		return ""
+	}
+	return baseURL() + "/unsubscribe?token=" + url.QueryEscape(UnsubscribeToken(address, category))
+}
+
+// SuppressionList says which addresses must not be sent email.
+type SuppressionList interface {
+	// IsSuppressed reports whether email in category must not be sent to
+	// address.
+	IsSuppressed(address, category string) (bool, error)
+}
+
+// WithSuppression returns a mailer sending with m, except that messages to
+// addresses list suppresses in the message's category are dropped.
+func WithSuppression(m Mailer, list SuppressionList) Mailer {
+	return &suppressingMailer{mailer: m, list: list}
+}
+
+type suppressingMailer struct {
+	mailer Mailer
+	list   SuppressionList
+}
+
+func (m *suppressingMailer) Send(ctx context.Context, msg Message) error {
+	suppressed, err := m.list.IsSuppressed(msg.To, msg.Category)
+	if err != nil {
+		return err
+	}
+	if suppressed {
+		log.Printf("Not sending %q to suppressed address %s", msg.Subject, msg.To)
+		return nil
+	}
+	return m.mailer.Send(ctx, msg)
+}
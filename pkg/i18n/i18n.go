@@ -0,0 +1,155 @@
+// Package i18n translates notifications and emails with the message
+// catalogs in catalogs/, one JSON file of message keys per locale. Messages
+// refer to parameters as {name}, or {name,style} for a time formatted with
+// the catalog's "format.<style>" layout.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+)
+
+// DefaultLocale is used for locales without a catalog, and for keys a
+// catalog is missing.
+const DefaultLocale = "en"
+
+//go:embed catalogs/*.json
+var catalogFiles embed.FS
+
+// catalogs maps each locale to its messages by key.
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]map[string]string {
+	files, err := catalogFiles.ReadDir("catalogs")
+	if err != nil {
+		panic(err)
+	}
+	res := make(map[string]map[string]string, len(files))
+	for _, f := range files {
+		b, err := catalogFiles.ReadFile(path.Join("catalogs", f.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(b, &messages); err != nil {
+			panic("i18n: parsing " + f.Name() + ": " + err.Error())
+		}
+		res[strings.TrimSuffix(f.Name(), ".json")] = messages
+	}
+	return res
+}
+
+// Keys returns the keys of locale's catalog, for checking that catalogs
+// are complete.
+func Keys(locale string) []string {
+	res := make([]string, 0, len(catalogs[locale]))
+	for k := range catalogs[locale] {
+		res = append(res, k)
+	}
+	return res
+}
+
+// Match returns the locale whose catalog is used for the language tag tag:
+// tag itself, else its language, so si-LK uses si, else DefaultLocale.
+func Match(tag string) string {
+	tag = strings.ToLower(tag)
+	if _, ok := catalogs[tag]; ok {
+		return tag
+	}
+	lang, _, _ := strings.Cut(tag, "-")
+	if _, ok := catalogs[lang]; ok {
+		return lang
+	}
+	return DefaultLocale
+}
+
+// Localizer translates messages into one locale, showing times in one time
+// zone.
+type Localizer struct {
+	Locale   string
+	Location *time.Location
+}
+
+// New returns a localizer for the language tag locale, showing times in
+// loc, or UTC if loc is nil.
+func New(locale string, loc *time.Location) Localizer {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return Localizer{Locale: Match(locale), Location: loc}
+}
+
+// For returns a localizer in u's locale and time zone.
+func For(u models.User) Localizer {
+	return New(u.Prefs().Locale, u.Location())
+}
+
+// Has reports whether key has a message.
+func (l Localizer) Has(key string) bool {
+	_, ok := l.message(key)
+	return ok
+}
+
+func (l Localizer) message(key string) (string, bool) {
+	if m, ok := catalogs[l.Locale][key]; ok {
+		return m, true
+	}
+	m, ok := catalogs[DefaultLocale][key]
+	return m, ok
+}
+
+var placeholder = regexp.MustCompile(`\{(\w+)(?:,(\w+))?\}`)
+
+// T returns the message for key with its placeholders replaced by params.
+// A key without a message is returned as is.
+func (l Localizer) T(key string, params map[string]string) string {
+	m, ok := l.message(key)
+	if !ok {
+		return key
+	}
+	return placeholder.ReplaceAllStringFunc(m, func(p string) string {
+		sub := placeholder.FindStringSubmatch(p)
+		v, ok := params[sub[1]]
+		if !ok {
+			return p
+		}
+		if sub[2] != "" {
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return l.Format(t, sub[2])
+			}
+		}
+		return v
+	})
+}
+
+// Format formats t in the localizer's time zone with the layout of the
+// catalog's "format.<style>" message.
+func (l Localizer) Format(t time.Time, style string) string {
+	layout, ok := l.message("format." + style)
+	if !ok {
+		layout = time.RFC3339
+	}
+	return t.In(l.Location).Format(layout)
+}
+
+// Time returns t as a parameter value for a {name,style} placeholder.
+func Time(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Notification returns n's text: its type's "notification.<type>" message
+// with its parameters, or the message it was stored with if it has no
+// parameters, as notifications created before they were translated do.
+func (l Localizer) Notification(n models.Notification) string {
+	key := "notification." + n.Type
+	if n.Params == nil || !l.Has(key) {
+		return n.Message
+	}
+	return l.T(key, n.Params)
+}
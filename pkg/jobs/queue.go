@@ -0,0 +1,151 @@
+// Package jobs runs background work as jobs persisted in the store, so work
+// survives restarts and failed work is retried with exponential backoff
+// until it runs out of attempts and is dead-lettered.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+)
+
+const (
+	DefaultMaxAttempts = 5
+	DefaultLease       = 5 * time.Minute
+
+	minBackoff = 30 * time.Second
+	maxBackoff = time.Hour
+)
+
+// Handler runs a job. A returned error fails the attempt. ctx ends when the
+// run the job is part of must stop, so handlers should give up their I/O
+// by then.
+type Handler func(ctx context.Context, job models.Job) error
+
+// permanentError marks a failure that retrying cannot fix.
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job is dead-lettered at once instead of being
+// retried.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// Queue enqueues jobs in a store and runs them with registered handlers.
+type Queue struct {
+	Store store.Store
+	// Lease is how long a claimed job may run before it is considered
+	// abandoned and may be claimed again.
+	Lease       time.Duration
+	MaxAttempts int
+	// Backoff returns how long to wait before retrying a job that has
+	// failed the given number of attempts.
+	Backoff func(attempts int) time.Duration
+	// Now returns the current time, deciding which jobs are due.
+	Now func() time.Time
+
+	handlers map[string]Handler
+}
+
+func New(s store.Store) *Queue {
+	return &Queue{
+		Store:       s,
+		Lease:       DefaultLease,
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     ExponentialBackoff,
+		Now:         time.Now,
+		handlers:    make(map[string]Handler),
+	}
+}
+
+// ExponentialBackoff doubles the wait from 30 seconds with each failed
+// attempt, up to an hour.
+func ExponentialBackoff(attempts int) time.Duration {
+	d := minBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// Handle registers the handler for jobs of kind.
+func (q *Queue) Handle(kind string, h Handler) {
+	q.handlers[kind] = h
+}
+
+// Enqueue schedules a job of kind to run at runAt. Jobs are unique by key:
+// while a job with the same key is stored, enqueueing another is a no-op.
+func (q *Queue) Enqueue(kind, key string, payload map[string]string, runAt time.Time) error {
+	_, _, err := q.Store.EnqueueJob(models.Job{
+		Kind:        kind,
+		Key:         key,
+		Payload:     payload,
+		MaxAttempts: q.MaxAttempts,
+		RunAt:       runAt,
+	})
+	return err
+}
+
+// RunDue claims up to limit jobs that are due and runs them one at a time
+// with ctx. It returns how many jobs were run.
+func (q *Queue) RunDue(ctx context.Context, limit int) (int, error) {
+	now := q.Now()
+	claimed, err := q.Store.ClaimJobs(now, q.Lease, limit)
+	for _, j := range claimed {
+		q.run(ctx, j)
+	}
+	return len(claimed), err
+}
+
+func (q *Queue) run(ctx context.Context, j models.Job) {
+	err := q.call(ctx, j)
+	if err == nil {
+		q.finished(j, q.Store.CompleteJob(j))
+		return
+	}
+
+	var permanent permanentError
+	if errors.As(err, &permanent) || j.Attempts >= j.MaxAttempts {
+		log.Printf("Job %s (%s) failed after %d attempts, dead-lettering: %v", j.ID, j.Kind, j.Attempts, err)
+		q.finished(j, q.Store.KillJob(j, err.Error()))
+	} else {
+		retryAt := q.Now().Add(q.Backoff(j.Attempts))
+		log.Printf("Job %s (%s) failed, retrying at %s: %v", j.ID, j.Kind, retryAt.Format(time.RFC3339), err)
+		q.finished(j, q.Store.RescheduleJob(j, retryAt, err.Error()))
+	}
+}
+
+// finished logs an error recording j's outcome. A job whose lease ran out
+// before it finished may have been claimed by another worker, which now
+// owns it, so its outcome is dropped.
+func (q *Queue) finished(j models.Job, err error) {
+	switch {
+	case errors.Is(err, store.ErrJobLost):
+		log.Printf("Job %s (%s) outlived its lease, leaving it to its new claim", j.ID, j.Kind)
+	case err != nil:
+		log.Printf("Error updating job %s: %v", j.ID, err)
+	}
+}
+
+// call runs j's handler, turning a panic into an error so one bad job
+// cannot stop the worker.
+func (q *Queue) call(ctx context.Context, j models.Job) (err error) {
+	h, ok := q.handlers[j.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for job kind %q", j.Kind))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h(ctx, j)
+}
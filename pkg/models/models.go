@@ -1,64 +1,391 @@
 package models
 
-import "time"
+import (
+	"time"
 
-// Task mirrors the frontend Task model
+	// Embed the zone database: serverless runtimes may not ship one.
+	_ "time/tzdata"
+)
+
+// Task mirrors the frontend Task model. The frontend's separate due date and
+// time are both derived from DueAt.
 type Task struct {
-	ID          string `json:"id" bson:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	CourseID    string `json:"courseId" bson:"courseId"`
-	UserID      string `json:"userId" bson:"userId"`
-	DueDate     string `json:"dueDate"`
-	DueTime     string `json:"dueTime"`
-	Completed   bool    `json:"completed"`
-	HasReminder bool    `json:"hasReminder"`
-	CompletedAt *string `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
-}
-
-// Course mirrors the frontend Course model
+	ID          string    `json:"id" bson:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CourseID    string    `json:"courseId" bson:"courseId"`
+	UserID      string    `json:"userId" bson:"userId"`
+	DueAt       time.Time `json:"dueAt" bson:"dueAt"`
+	Completed   bool      `json:"completed"`
+	HasReminder bool      `json:"hasReminder"`
+	// Reminders are only sent when HasReminder is set. Without any of its
+	// own, a task gets one at the owner's preferred lead time.
+	Reminders   []Reminder `json:"reminders,omitempty" bson:"reminders,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt" bson:"createdAt"`
+}
+
+// RemindersFor returns the reminders to send for t, given its owner's
+// preferences.
+func (t Task) RemindersFor(p Preferences) []Reminder {
+	if !t.HasReminder {
+		return nil
+	}
+	if len(t.Reminders) > 0 {
+		return t.Reminders
+	}
+	return []Reminder{{Before: p.TaskReminderLead}}
+}
+
+// Course mirrors the frontend Course model. Task totals are not stored on the
+// course; they are computed with Store.CountTasksByCourse.
 type Course struct {
-	ID             string `json:"id" bson:"id"`
-	Name           string `json:"name"`
-	Color          string `json:"color"`
-	UserID         string `json:"userId" bson:"userId"`
-	TotalTasks     int    `json:"totalTasks"`
-	CompletedTasks int    `json:"completedTasks"`
+	ID     string `json:"id" bson:"id"`
+	Name   string `json:"name"`
+	Color  string `json:"color"`
+	UserID string `json:"userId" bson:"userId"`
 }
 
-// Event mirrors the frontend Event model
+// Event mirrors the frontend Event model. The frontend's date, start and end
+// times are derived from StartsAt and EndsAt.
 type Event struct {
-	ID          string `json:"id" bson:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	CourseID    string `json:"courseId"`
-	UserID      string `json:"userId" bson:"userId"`
-	Date        string `json:"date"`
-	StartTime   string `json:"startTime"`
-	EndTime     string `json:"endTime"`
-	Type        string `json:"type"`
+	ID          string    `json:"id" bson:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CourseID    string    `json:"courseId"`
+	UserID      string    `json:"userId" bson:"userId"`
+	StartsAt    time.Time `json:"startsAt" bson:"startsAt"`
+	EndsAt      time.Time `json:"endsAt" bson:"endsAt"`
+	Type        string    `json:"type"`
+	// Reminders replace the default reminder at the owner's preferred lead
+	// time when set. An empty, non-nil list turns reminders off.
+	Reminders []Reminder `json:"reminders" bson:"reminders"`
+}
+
+// RemindersFor returns the reminders to send for e, given its owner's
+// preferences.
+func (e Event) RemindersFor(p Preferences) []Reminder {
+	if e.Reminders != nil {
+		return e.Reminders
+	}
+	return []Reminder{{Before: p.EventReminderLead}}
+}
+
+// Reminder is a time to remind the user of a task or event: either Before
+// its due or start time, or At a fixed instant.
+type Reminder struct {
+	Before time.Duration `json:"before,omitempty" bson:"before,omitempty"`
+	At     *time.Time    `json:"at,omitempty" bson:"at,omitempty"`
+}
+
+// FireAt returns when the reminder is due for an item due or starting at t.
+func (r Reminder) FireAt(t time.Time) time.Time {
+	if r.At != nil {
+		return *r.At
+	}
+	return t.Add(-r.Before)
 }
 
 // User model for authentication
 type User struct {
-	ID       string `json:"id" bson:"id"`
-	Name     string `json:"name"`
+	ID                string `json:"id" bson:"id"`
+	Name              string `json:"name"`
 	Email             string `json:"email"`
 	Password          string `json:"password" bson:"password"`
 	RefreshToken      string `json:"refreshToken,omitempty" bson:"refreshToken,omitempty"`
 	IsVerified        bool   `json:"isVerified" bson:"isVerified"`
 	VerificationToken string `json:"-" bson:"verificationToken"`
+	// TimeZone is an IANA zone name such as "Asia/Colombo". Task and event
+	// wall-clock times are interpreted in it; empty means UTC.
+	TimeZone string `json:"timeZone,omitempty" bson:"timeZone,omitempty"`
+	// Preferences is nil until the user changes a setting; use Prefs.
+	Preferences *Preferences `json:"preferences,omitempty" bson:"preferences,omitempty"`
+	// DigestSentAt is the scheduled time of the last digest sent.
+	DigestSentAt *time.Time `json:"digestSentAt,omitempty" bson:"digestSentAt,omitempty"`
+}
+
+// Notification channels a user can receive reminders on.
+const (
+	ChannelInApp = "IN_APP"
+	ChannelEmail = "EMAIL"
+	ChannelPush  = "PUSH"
+)
+
+// MaxReminderLead bounds reminder lead times, and so how far ahead the worker
+// has to look for upcoming tasks and events.
+const MaxReminderLead = 7 * 24 * time.Hour
+
+// Preferences are a user's reminder and display settings.
+type Preferences struct {
+	// How long before a task is due or an event starts to remind the user.
+	TaskReminderLead  time.Duration `json:"taskReminderLead" bson:"taskReminderLead"`
+	EventReminderLead time.Duration `json:"eventReminderLead" bson:"eventReminderLead"`
+	// EmailDelay is how long a notification may stay unread in the app
+	// before it is also sent by email.
+	EmailDelay time.Duration `json:"emailDelay" bson:"emailDelay"`
+	// QuietHours, in the user's time zone, hold back email and push
+	// delivery. Nil means no quiet hours.
+	QuietHours *QuietHours  `json:"quietHours,omitempty" bson:"quietHours,omitempty"`
+	Channels   []string     `json:"channels" bson:"channels"`
+	WeekStart  time.Weekday `json:"weekStart" bson:"weekStart"`
+	Locale     string       `json:"locale" bson:"locale"`
+	// Digest, when set, batches email into a scheduled digest instead of
+	// one email per notification. Nil means no digest.
+	Digest *Digest `json:"digest,omitempty" bson:"digest,omitempty"`
+}
+
+// Digest frequencies.
+const (
+	DigestDaily  = "DAILY"
+	DigestWeekly = "WEEKLY"
+)
+
+// Digest schedules a summary email. A weekly digest is sent on the first
+// day of the user's week.
+type Digest struct {
+	Frequency string `json:"frequency" bson:"frequency"`
+	// At is the local time to send at, in minutes after midnight.
+	At int `json:"at" bson:"at"`
+}
+
+// LastDue returns the latest time at or before now that the digest is
+// scheduled for, in loc, for a user whose week starts on weekStart.
+func (d Digest) LastDue(now time.Time, loc *time.Location, weekStart time.Weekday) time.Time {
+	local := now.In(loc)
+	for i := 0; ; i++ {
+		// time.Date normalizes the day, so this steps back over month ends
+		t := time.Date(local.Year(), local.Month(), local.Day()-i, d.At/60, d.At%60, 0, 0, loc)
+		if t.After(now) {
+			continue
+		}
+		if d.Frequency != DigestWeekly || t.Weekday() == weekStart {
+			return t
+		}
+	}
+}
+
+// QuietHours is a daily window given in minutes after local midnight. The
+// window wraps past midnight when End is before Start, e.g. 22:00 to 07:00.
+type QuietHours struct {
+	Start int `json:"start" bson:"start"`
+	End   int `json:"end" bson:"end"`
+}
+
+// DefaultPreferences are used for users who have not changed any setting.
+func DefaultPreferences() Preferences {
+	return Preferences{
+		TaskReminderLead:  24 * time.Hour,
+		EventReminderLead: 24 * time.Hour,
+		EmailDelay:        time.Hour,
+		Channels:          []string{ChannelInApp, ChannelEmail},
+		WeekStart:         time.Monday,
+		Locale:            "en",
+	}
+}
+
+// Prefs returns the user's preferences, or the defaults if none are stored.
+func (u User) Prefs() Preferences {
+	if u.Preferences == nil {
+		return DefaultPreferences()
+	}
+	return *u.Preferences
+}
+
+// HasChannel reports whether reminders should be delivered on channel.
+func (p Preferences) HasChannel(channel string) bool {
+	for _, c := range p.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// InQuietHours reports whether t falls within the quiet hours, read in loc.
+func (p Preferences) InQuietHours(t time.Time, loc *time.Location) bool {
+	if p.QuietHours == nil || p.QuietHours.Start == p.QuietHours.End {
+		return false
+	}
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	start, end := p.QuietHours.Start, p.QuietHours.End
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// Location returns the user's time zone, or UTC if it is unset or unknown.
+func (u User) Location() *time.Location {
+	if u.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 type Notification struct {
-	ID        string  `json:"id" bson:"id"`
-	UserID    string  `json:"userId" bson:"userId"`
-	Message   string  `json:"message" bson:"message"`
-	Type      string  `json:"type" bson:"type"` // "TASK_DUE", "EVENT_START"
-	ReferenceID string `json:"referenceId" bson:"referenceId"` // ID of Task or Event
-	Read      bool    `json:"read" bson:"read"`
-	CreatedAt string  `json:"createdAt" bson:"createdAt"`
-	Emailed   bool    `json:"emailed" bson:"emailed"`
+	ID     string `json:"id" bson:"id"`
+	UserID string `json:"userId" bson:"userId"`
+	// Message is the text of notifications without Params, such as those
+	// created before notifications were translated. Others are rendered
+	// from Type and Params in the reader's locale.
+	Message     string            `json:"message,omitempty" bson:"message,omitempty"`
+	Type        string            `json:"type" bson:"type"` // "TASK_DUE", "EVENT_START"
+	Params      map[string]string `json:"params,omitempty" bson:"params,omitempty"`
+	ReferenceID string            `json:"referenceId" bson:"referenceId"` // ID of Task or Event
+	Read        bool              `json:"read" bson:"read"`
+	CreatedAt   time.Time         `json:"createdAt" bson:"createdAt"`
+	Emailed     bool              `json:"emailed" bson:"emailed"`
+	// DedupKey identifies the reminder the notification was sent for, so
+	// each reminder is sent at most once.
+	DedupKey string `json:"dedupKey,omitempty" bson:"dedupKey,omitempty"`
+	// SnoozedUntil is when a snoozed notification is to be sent again.
+	SnoozedUntil *time.Time `json:"snoozedUntil,omitempty" bson:"snoozedUntil,omitempty"`
+}
+
+// Job statuses. Jobs are removed once they succeed, so a job is either
+// waiting to run, running, or dead after using up its attempts.
+const (
+	JobPending = "PENDING"
+	JobRunning = "RUNNING"
+	JobDead    = "DEAD"
+)
+
+// Job is a unit of background work run by the worker's job queue.
+type Job struct {
+	ID   string `json:"id" bson:"id"`
+	Kind string `json:"kind" bson:"kind"`
+	// Key is unique among stored jobs, so enqueueing the same work twice
+	// while the first job is pending, running or dead is a no-op.
+	Key         string            `json:"key" bson:"key"`
+	Payload     map[string]string `json:"payload,omitempty" bson:"payload,omitempty"`
+	Status      string            `json:"status" bson:"status"`
+	Attempts    int               `json:"attempts" bson:"attempts"`
+	MaxAttempts int               `json:"maxAttempts" bson:"maxAttempts"`
+	// RunAt is when a pending job is next due. LockedUntil is when a
+	// running job's claim expires, after which another worker may take it
+	// over, e.g. after a crash.
+	RunAt       time.Time `json:"runAt" bson:"runAt"`
+	LockedUntil time.Time `json:"lockedUntil" bson:"lockedUntil"`
+	LastError   string    `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// PushSubscription is a browser's Web Push subscription, registered once
+// for each device a user turns push notifications on for.
+type PushSubscription struct {
+	ID     string `json:"id" bson:"id"`
+	UserID string `json:"userId" bson:"userId"`
+	// Endpoint is the push service URL messages are posted to. It is
+	// unique to the subscription.
+	Endpoint string `json:"endpoint" bson:"endpoint"`
+	// P256dh and Auth are the keys payloads are encrypted with, base64url
+	// encoded as browsers give them.
+	P256dh string `json:"p256dh" bson:"p256dh"`
+	Auth   string `json:"auth" bson:"auth"`
+	// Device is a label for the device, e.g. "Firefox on Android".
+	Device    string    `json:"device,omitempty" bson:"device,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Platforms of devices registered for native push notifications.
+const (
+	PlatformAndroid = "ANDROID"
+	PlatformIOS     = "IOS"
+)
+
+// DeviceToken is a mobile app's push registration token, registered once
+// for each device a user signs in to the app on.
+type DeviceToken struct {
+	ID     string `json:"id" bson:"id"`
+	UserID string `json:"userId" bson:"userId"`
+	// Token is the push provider's registration token for the app on the
+	// device. It is unique to the device.
+	Token    string `json:"token" bson:"token"`
+	Platform string `json:"platform" bson:"platform"`
+	// Device is a label for the device, e.g. "Pixel 8".
+	Device    string    `json:"device,omitempty" bson:"device,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Webhook event types, which webhooks subscribe to. WebhookPing is only
+// sent by testing a webhook.
+const (
+	WebhookTaskCreated         = "task.created"
+	WebhookTaskCompleted       = "task.completed"
+	WebhookEventUpdated        = "event.updated"
+	WebhookNotificationCreated = "notification.created"
+	WebhookPing                = "ping"
+)
+
+// Webhook is an endpoint a user has registered to be sent their changes of
+// the types in Events.
+type Webhook struct {
+	ID     string   `json:"id" bson:"id"`
+	UserID string   `json:"userId" bson:"userId"`
+	URL    string   `json:"url" bson:"url"`
+	Events []string `json:"events" bson:"events"`
+	// Secret is the key deliveries are signed with.
+	Secret      string    `json:"secret" bson:"secret"`
+	Description string    `json:"description,omitempty" bson:"description,omitempty"`
+	Active      bool      `json:"active" bson:"active"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Subscribed reports whether w is active and subscribed to event.
+func (w Webhook) Subscribed(event string) bool {
+	if !w.Active {
+		return false
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery logs one attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	ID        string `json:"id" bson:"id"`
+	WebhookID string `json:"webhookId" bson:"webhookId"`
+	// EventID identifies the event; it is the same for every attempt.
+	EventID string `json:"eventId" bson:"eventId"`
+	Event   string `json:"event" bson:"event"`
+	Attempt int    `json:"attempt" bson:"attempt"`
+	// StatusCode is the endpoint's response status, or 0 if there was no
+	// response.
+	StatusCode int           `json:"statusCode,omitempty" bson:"statusCode,omitempty"`
+	Error      string        `json:"error,omitempty" bson:"error,omitempty"`
+	Duration   time.Duration `json:"duration" bson:"duration"`
+	CreatedAt  time.Time     `json:"createdAt" bson:"createdAt"`
+}
+
+// Succeeded reports whether the endpoint accepted the delivery.
+func (d WebhookDelivery) Succeeded() bool {
+	return d.Error == "" && d.StatusCode >= 200 && d.StatusCode < 300
+}
+
+// Suppression reasons. An unsubscribe covers one category of email; a
+// bounce or complaint covers every email to the address.
+const (
+	SuppressionUnsubscribed = "UNSUBSCRIBED"
+	SuppressionBounced      = "BOUNCED"
+	SuppressionComplained   = "COMPLAINED"
+)
+
+// Suppression stops email in Category, or in every category if it is
+// empty, from being sent to Email.
+type Suppression struct {
+	Email     string    `json:"email" bson:"email"`
+	Category  string    `json:"category" bson:"category"`
+	Reason    string    `json:"reason" bson:"reason"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
 }
 
 // Claims used for jwt
@@ -68,5 +395,4 @@ type Notification struct {
 type ServerConfig struct {
 	Addr      string
 	JWTSecret string
-	Now       func() time.Time
 }
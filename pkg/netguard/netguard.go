@@ -0,0 +1,85 @@
+// Package netguard keeps requests to URLs that users give, such as webhooks
+// and push subscription endpoints, from reaching the server's own network.
+// Hosts are checked when a URL is registered and again when a connection
+// is made, since DNS may resolve differently by then.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrNotPublic is returned for a host or address on a loopback, private,
+// link-local or unspecified network.
+var ErrNotPublic = errors.New("address is not public")
+
+// allowPrivate reports whether ALLOW_PRIVATE_HOSTS lets users' URLs reach
+// non-public addresses, for local development against local receivers.
+func allowPrivate() bool {
+	return os.Getenv("ALLOW_PRIVATE_HOSTS") == "true"
+}
+
+// Public reports whether ip may be connected to for a user's URL.
+func Public(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsUnspecified()
+}
+
+// CheckHost returns ErrNotPublic if host is, or resolves to, an address
+// that is not public.
+func CheckHost(host string) error {
+	if allowPrivate() {
+		return nil
+	}
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = net.LookupIP(host); err != nil {
+			return fmt.Errorf("resolving %s: %w", host, err)
+		}
+	}
+	for _, ip := range ips {
+		if !Public(ip) {
+			return fmt.Errorf("%s: %w", host, ErrNotPublic)
+		}
+	}
+	return nil
+}
+
+// Control is a net.Dialer Control function refusing connections to
+// addresses that are not public.
+func Control(network, address string, _ syscall.RawConn) error {
+	if allowPrivate() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !Public(ip) {
+		return fmt.Errorf("dialing %s: %w", address, ErrNotPublic)
+	}
+	return nil
+}
+
+// NewClient returns a client that only connects to public addresses, does
+// not follow redirects and gives up after timeout. It uses no proxy, which
+// would be connected to instead of the URL's host.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: Control}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// A redirect could lead anywhere, including where the URL could not
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
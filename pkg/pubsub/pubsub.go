@@ -0,0 +1,84 @@
+// Package pubsub is an in-process publish/subscribe hub used to push data
+// changes to GraphQL subscriptions. Events are scoped to a user so that a
+// subscriber only ever sees its own data.
+package pubsub
+
+import "sync"
+
+const (
+	TopicNotification = "notification"
+	TopicTask         = "task"
+	TopicEvent        = "event"
+)
+
+const (
+	ActionCreated = "CREATED"
+	ActionUpdated = "UPDATED"
+	ActionDeleted = "DELETED"
+)
+
+// Event is a change published to the subscribers of a user's topic.
+type Event struct {
+	Topic   string
+	UserID  string
+	Action  string
+	Payload any
+}
+
+// subscriberBuffer is how many events a slow subscriber may lag behind
+// before further events to it are dropped.
+const subscriberBuffer = 16
+
+type subscription struct {
+	userID string
+	topic  string
+	ch     chan Event
+}
+
+// Broker fans published events out to matching subscribers. Publishing never
+// blocks; events to a subscriber whose buffer is full are dropped.
+type Broker struct {
+	mu   sync.RWMutex
+	next int
+	subs map[int]*subscription
+}
+
+func New() *Broker {
+	return &Broker{subs: make(map[int]*subscription)}
+}
+
+// Subscribe returns a channel receiving the user's events on topic and a
+// function that cancels the subscription and closes the channel.
+func (b *Broker) Subscribe(userID, topic string) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	sub := &subscription{userID: userID, topic: topic, ch: make(chan Event, subscriberBuffer)}
+	b.subs[id] = sub
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs, id)
+			close(sub.ch)
+		})
+	}
+	return sub.ch, cancel
+}
+
+func (b *Broker) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subs {
+		if sub.userID != e.UserID || sub.topic != e.Topic {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+		}
+	}
+}
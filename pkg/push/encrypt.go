@@ -0,0 +1,137 @@
+package push
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Payloads are encrypted as a single aes128gcm record (RFC 8188). Push
+// services accept bodies of 4096 bytes, which leaves MaxPayload for the
+// message after the header, the GCM tag and the padding delimiter.
+const (
+	recordSize = 4096
+	headerSize = 16 + 4 + 1 + 65
+	MaxPayload = recordSize - headerSize - 16 - 1
+)
+
+var errMalformed = errors.New("malformed aes128gcm message")
+
+// Encrypt encrypts payload for the user agent with public key uaPublic and
+// authentication secret authSecret, as in RFC 8291, with a new ephemeral key
+// and salt.
+func Encrypt(uaPublic, authSecret, payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayload {
+		return nil, fmt.Errorf("push payload of %d bytes exceeds %d", len(payload), MaxPayload)
+	}
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encrypt(uaPublic, authSecret, payload, asKey, salt)
+}
+
+func encrypt(uaPublic, authSecret, payload []byte, asKey *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	ua, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	secret, err := asKey.ECDH(ua)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+	gcm, nonce, err := contentCipher(secret, authSecret, salt, uaPublic, asPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	// The 0x02 delimiter marks the last and only record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts a message encrypted with Encrypt for the user agent
+// with private key uaKey and authentication secret authSecret. It is the
+// browser's side of RFC 8291, for push service stubs in tests.
+func Decrypt(uaKey *ecdh.PrivateKey, authSecret, body []byte) ([]byte, error) {
+	if len(body) < 21 {
+		return nil, errMalformed
+	}
+	salt, idLen := body[:16], int(body[20])
+	if len(body) < 21+idLen {
+		return nil, errMalformed
+	}
+	asPublic, ciphertext := body[21:21+idLen], body[21+idLen:]
+	as, err := ecdh.P256().NewPublicKey(asPublic)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := uaKey.ECDH(as)
+	if err != nil {
+		return nil, err
+	}
+	gcm, nonce, err := contentCipher(secret, authSecret, salt, uaKey.PublicKey().Bytes(), asPublic)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Strip the padding, which ends at the delimiter
+	i := len(plaintext) - 1
+	for i >= 0 && plaintext[i] == 0 {
+		i--
+	}
+	if i < 0 || plaintext[i] != 0x02 {
+		return nil, errMalformed
+	}
+	return plaintext[:i], nil
+}
+
+// contentCipher derives the content encryption key and nonce from the ECDH
+// secret between the two keys, as in RFC 8291 section 3.4.
+func contentCipher(ecdhSecret, authSecret, salt, uaPublic, asPublic []byte) (cipher.AEAD, []byte, error) {
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gcm, nonce, nil
+}
@@ -0,0 +1,302 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// DefaultFCMEndpoint is the base URL of the FCM HTTP v1 API.
+	DefaultFCMEndpoint = "https://fcm.googleapis.com"
+	fcmScope           = "https://www.googleapis.com/auth/firebase.messaging"
+	// tokenRefreshMargin is how long before it expires an access token is
+	// replaced.
+	tokenRefreshMargin = time.Minute
+)
+
+// ServiceAccount is a Google service account key file's credentials.
+type ServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	// TokenURI is the OAuth 2.0 endpoint access tokens are fetched from.
+	TokenURI string `json:"token_uri"`
+}
+
+// ReadServiceAccount reads a service account key file, as downloaded from
+// the Firebase console.
+func ReadServiceAccount(path string) (ServiceAccount, error) {
+	var sa ServiceAccount
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return sa, err
+	}
+	if err := json.Unmarshal(b, &sa); err != nil {
+		return sa, fmt.Errorf("parsing service account %s: %w", path, err)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" || sa.TokenURI == "" {
+		return sa, fmt.Errorf("service account %s is missing client_email, private_key or token_uri", path)
+	}
+	return sa, nil
+}
+
+// FCMFromEnv returns an FCM provider with the service account in
+// FCM_CREDENTIALS_FILE, or nil if it is not set. FCM_PROJECT_ID overrides
+// the service account's project, and FCM_ENDPOINT the API's base URL, e.g.
+// to send to a local fake.
+func FCMFromEnv() (*FCM, error) {
+	path := os.Getenv("FCM_CREDENTIALS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	sa, err := ReadServiceAccount(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := NewFCM(sa)
+	if err != nil {
+		return nil, err
+	}
+	if project := os.Getenv("FCM_PROJECT_ID"); project != "" {
+		f.ProjectID = project
+	}
+	if f.ProjectID == "" {
+		return nil, errors.New("FCM_PROJECT_ID must be set if the service account has no project_id")
+	}
+	f.Endpoint = os.Getenv("FCM_ENDPOINT")
+	return f, nil
+}
+
+// NewFCM returns an FCM provider authenticating as sa, sending to sa's
+// project.
+func NewFCM(sa ServiceAccount) (*FCM, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(sa.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parsing service account key: %w", err)
+	}
+	return &FCM{
+		ProjectID: sa.ProjectID,
+		account:   sa,
+		key:       key,
+	}, nil
+}
+
+// fcmClient talks to the configured FCM endpoint, which may be a local
+// fake, so unlike defaultClient it may connect to any address.
+var fcmClient = &http.Client{Timeout: 30 * time.Second}
+
+// FCM sends messages with the Firebase Cloud Messaging HTTP v1 API, which
+// delivers to Android devices itself and to iOS devices through APNs.
+type FCM struct {
+	ProjectID string
+	// Endpoint is the API's base URL; empty means DefaultFCMEndpoint.
+	Endpoint string
+	// Client sends the messages and fetches access tokens; nil uses one
+	// with a 30 second timeout.
+	Client *http.Client
+	// Now returns the current time, used for access token expiry; nil means
+	// time.Now.
+	Now func() time.Time
+
+	account ServiceAccount
+	key     *rsa.PrivateKey
+
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+var _ PushProvider = (*FCM)(nil)
+
+func (f *FCM) client() *http.Client {
+	if f.Client != nil {
+		return f.Client
+	}
+	return fcmClient
+}
+
+func (f *FCM) now() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	return time.Now()
+}
+
+// fcmMessage is the body of a messages:send request.
+type fcmMessage struct {
+	Message struct {
+		Token        string            `json:"token"`
+		Notification fcmNotification   `json:"notification"`
+		Data         map[string]string `json:"data,omitempty"`
+		Android      struct {
+			Priority string `json:"priority"`
+		} `json:"android"`
+		APNS struct {
+			Headers map[string]string `json:"headers"`
+		} `json:"apns"`
+	} `json:"message"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// fcmError is the body of a failed request.
+type fcmError struct {
+	Error struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+			// FieldViolations name the invalid fields of an
+			// INVALID_ARGUMENT request.
+			FieldViolations []struct {
+				Field string `json:"field"`
+			} `json:"fieldViolations"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// Send sends msg to the device with registration token token, as a high
+// priority notification. It gives up once ctx is done.
+func (f *FCM) Send(ctx context.Context, token string, msg Message) error {
+	var body fcmMessage
+	body.Message.Token = token
+	body.Message.Notification = fcmNotification{Title: msg.Title, Body: msg.Body}
+	body.Message.Data = msg.Data
+	body.Message.Android.Priority = "HIGH"
+	body.Message.APNS.Headers = map[string]string{"apns-priority": "10"}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := f.post(ctx, b)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		// The access token may have been revoked before it expired
+		resp.Body.Close()
+		f.mu.Lock()
+		f.accessToken = ""
+		f.mu.Unlock()
+		resp, err = f.post(ctx, b)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	var fe fcmError
+	json.Unmarshal(respBody, &fe)
+	for _, d := range fe.Error.Details {
+		// The app was uninstalled, or the token is for another project
+		if d.ErrorCode == "UNREGISTERED" || d.ErrorCode == "SENDER_ID_MISMATCH" {
+			return ErrGone
+		}
+		// The token is not one FCM issued, so it will never be valid
+		for _, v := range d.FieldViolations {
+			if fe.Error.Status == "INVALID_ARGUMENT" && v.Field == "message.token" {
+				return ErrGone
+			}
+		}
+	}
+	if fe.Error.Status == "INVALID_ARGUMENT" && strings.Contains(fe.Error.Message, "registration token") {
+		return ErrGone
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrGone
+	}
+	msgText := fe.Error.Message
+	if msgText == "" {
+		msgText = string(respBody)
+	}
+	return &StatusError{StatusCode: resp.StatusCode, Body: msgText}
+}
+
+func (f *FCM) post(ctx context.Context, body []byte) (*http.Response, error) {
+	accessToken, err := f.token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting FCM access token: %w", err)
+	}
+	endpoint := f.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultFCMEndpoint
+	}
+	target := strings.TrimSuffix(endpoint, "/") + "/v1/projects/" + url.PathEscape(f.ProjectID) + "/messages:send"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	return f.client().Do(req)
+}
+
+// token returns an OAuth 2.0 access token for the service account, fetching
+// a new one with a signed JWT (RFC 7523) once the last has nearly expired.
+func (f *FCM) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	if f.accessToken != "" && now.Add(tokenRefreshMargin).Before(f.expiry) {
+		return f.accessToken, nil
+	}
+
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.key)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	// Not a StatusError, which would be the FCM API rejecting the message:
+	// the message may well be sent once a token can be had, so the failure
+	// is worth retrying
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, respBody)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(respBody, &token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("unexpected token response: %s", respBody)
+	}
+	f.accessToken = token.AccessToken
+	f.expiry = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
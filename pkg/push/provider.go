@@ -0,0 +1,22 @@
+package push
+
+import "context"
+
+// Message is a notification for a mobile app.
+type Message struct {
+	Title string
+	Body  string
+	// Data is passed to the app with the notification, e.g. the ID of the
+	// task to open when it is tapped.
+	Data map[string]string
+}
+
+// PushProvider delivers messages to mobile apps by their devices'
+// registration tokens, as FCM does for Android and, through APNs, for iOS.
+type PushProvider interface {
+	// Send sends msg to the device with registration token token. It
+	// returns ErrGone if the token is no longer valid or never was, and a
+	// *StatusError if the provider rejects the message. It gives up once
+	// ctx is done.
+	Send(ctx context.Context, token string, msg Message) error
+}
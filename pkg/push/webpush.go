@@ -0,0 +1,218 @@
+// Package push delivers notifications to users' devices. Web Push sends
+// them to browsers through their push services, encrypted as in RFC 8291
+// and signed with the server's VAPID key (RFC 8292). A PushProvider, such as
+// FCM, sends them to mobile apps.
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/netguard"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrGone is returned for a subscription the push service no longer knows,
+// which should be deleted.
+var ErrGone = errors.New("push subscription has expired or been unsubscribed")
+
+// StatusError is a push service's rejection of a message.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("push service returned %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether sending again later may succeed.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+const (
+	// DefaultTTL is how long push services keep a message for a device that
+	// is offline.
+	DefaultTTL = 24 * time.Hour
+	// vapidExpiry is the lifetime of VAPID tokens; RFC 8292 allows 24h.
+	vapidExpiry = 12 * time.Hour
+)
+
+// VAPID identifies the server to push services.
+type VAPID struct {
+	PrivateKey *ecdsa.PrivateKey
+	// Subject is a mailto: or https: URL the push service can contact the
+	// server's operator at.
+	Subject string
+}
+
+// PublicKey returns the key browsers subscribe with, as their
+// applicationServerKey: the uncompressed P-256 point, base64url encoded.
+func (v *VAPID) PublicKey() string {
+	pub, err := v.PrivateKey.PublicKey.ECDH()
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(pub.Bytes())
+}
+
+// authorization returns the Authorization header for a message to endpoint.
+func (v *VAPID) authorization(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+		Audience:  jwt.ClaimStrings{u.Scheme + "://" + u.Host},
+		ExpiresAt: jwt.NewNumericDate(now.Add(vapidExpiry)),
+		Subject:   v.Subject,
+	})
+	signed, err := token.SignedString(v.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("vapid t=%s, k=%s", signed, v.PublicKey()), nil
+}
+
+// ParseVAPIDKey parses a base64url encoded P-256 private key, as made by
+// GenerateVAPIDKey.
+func ParseVAPIDKey(s string) (*ecdsa.PrivateKey, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding VAPID key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("parsing VAPID key: %w", err)
+	}
+	// x509 is the only way to turn an ECDH key into an ECDSA one
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.(*ecdsa.PrivateKey), nil
+}
+
+// GenerateVAPIDKey returns a new VAPID private key, base64url encoded.
+func GenerateVAPIDKey() (string, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// WebPushFromEnv returns a WebPush sender with the VAPID key in
+// VAPID_PRIVATE_KEY and the contact in VAPID_SUBJECT, or nil if no key is
+// set.
+func WebPushFromEnv() (*WebPush, error) {
+	private := os.Getenv("VAPID_PRIVATE_KEY")
+	if private == "" {
+		return nil, nil
+	}
+	key, err := ParseVAPIDKey(private)
+	if err != nil {
+		return nil, err
+	}
+	subject := os.Getenv("VAPID_SUBJECT")
+	if subject == "" {
+		return nil, fmt.Errorf("VAPID_SUBJECT must be set to a mailto: or https: URL")
+	}
+	return &WebPush{VAPID: &VAPID{PrivateKey: key, Subject: subject}}, nil
+}
+
+// WebPush sends messages to browsers' push subscriptions.
+type WebPush struct {
+	VAPID *VAPID
+	// Client sends the messages; nil uses one with a 30 second timeout
+	// that only connects to public addresses.
+	Client *http.Client
+	// TTL is how long the push service keeps a message for an offline
+	// device; zero means DefaultTTL.
+	TTL time.Duration
+	// Now returns the current time, used for VAPID token expiry; nil means
+	// time.Now.
+	Now func() time.Time
+}
+
+// defaultClient only connects to public addresses, since push
+// subscription endpoints are given by users.
+var defaultClient = netguard.NewClient(30 * time.Second)
+
+// Send encrypts payload for sub and posts it to sub's push service. It
+// returns ErrGone if the subscription no longer exists, and a *StatusError
+// if the push service rejects the message. It gives up once ctx is done.
+func (p *WebPush) Send(ctx context.Context, sub models.PushSubscription, payload []byte) error {
+	uaPublic, err := base64.RawURLEncoding.DecodeString(sub.P256dh)
+	if err != nil {
+		return fmt.Errorf("decoding p256dh key: %w", err)
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(sub.Auth)
+	if err != nil {
+		return fmt.Errorf("decoding auth secret: %w", err)
+	}
+	body, err := Encrypt(uaPublic, authSecret, payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+	authorization, err := p.VAPID.authorization(sub.Endpoint, now())
+	if err != nil {
+		return err
+	}
+	ttl := p.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Urgency", "high")
+
+	client := p.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	default:
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+}
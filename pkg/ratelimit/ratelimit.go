@@ -0,0 +1,78 @@
+// Package ratelimit provides keyed token-bucket rate limiters.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter grants each key a budget of requests that refills continuously.
+// A nil *Limiter allows everything, which is how limits are disabled.
+type Limiter struct {
+	rate  float64 // tokens added per second
+	burst float64 // bucket capacity
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// PerMinute returns a limiter allowing n requests per minute per key, with
+// bursts of up to n. It returns nil (no limit) when n <= 0.
+func PerMinute(n int) *Limiter {
+	if n <= 0 {
+		return nil
+	}
+	return &Limiter{
+		rate:    float64(n) / 60,
+		burst:   float64(n),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes one token from key's bucket. When the bucket is empty it
+// returns false and how long until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep drops buckets that have refilled completely, bounding memory use
+// to the keys active within roughly the last refill period.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for k, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, k)
+		}
+	}
+}
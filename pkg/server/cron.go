@@ -0,0 +1,64 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/worker"
+)
+
+// defaultCronBudget keeps a cron tick inside the default serverless function
+// timeout.
+const defaultCronBudget = 8 * time.Second
+
+// cronTickResponse is the JSON summary returned by the cron tick endpoint.
+type cronTickResponse struct {
+	Skipped    bool  `json:"skipped"`
+	JobsRun    int   `json:"jobsRun"`
+	OutOfTime  bool  `json:"outOfTime"`
+	DurationMs int64 `json:"durationMs"`
+}
+
+// cronTickHandler runs one worker cycle within budget for deployments where
+// the worker cannot run in the background, such as Vercel. Callers must send
+// the shared secret as "Authorization: Bearer <secret>", which is how Vercel
+// Cron authenticates its requests. With no secret configured every request
+// is refused.
+func cronTickHandler(w *worker.Worker, secret string, budget time.Duration) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if secret == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		summary, err := w.RunCycle(budget)
+		if err != nil {
+			log.Printf("Error running cron tick: %v", err)
+			http.Error(rw, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(cronTickResponse{
+			Skipped:    summary.Skipped,
+			JobsRun:    summary.JobsRun,
+			OutOfTime:  summary.OutOfTime,
+			DurationMs: summary.Duration.Milliseconds(),
+		})
+	})
+}
+
+// cronBudgetFromEnv reads CRON_TICK_BUDGET as a Go duration such as "8s".
+func cronBudgetFromEnv() time.Duration {
+	d, err := time.ParseDuration(os.Getenv("CRON_TICK_BUDGET"))
+	if err != nil || d <= 0 {
+		return defaultCronBudget
+	}
+	return d
+}
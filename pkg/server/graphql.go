@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/RandithaK/StudyBuddy_Backend/graph"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/auth"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/email"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/webhook"
+	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// newGraphQLServer builds the GraphQL handler: the transports of gqlgen's
+// default server plus an authenticated websocket transport for subscriptions,
+// with depth and complexity limits and persisted queries applied.
+func newGraphQLServer(s *store.PublishingStore, mailer email.Mailer, vapidPublicKey string, webhooks *webhook.Sender, limits GraphQLLimits, pq PersistedQueryConfig) *handler.Server {
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers: &graph.Resolver{
+			Store:  s,
+			PubSub: s.Broker(),
+			Mailer: mailer,
+			Admins: adminEmailsFromEnv(),
+
+			VAPIDPublicKey: vapidPublicKey,
+			Webhooks:       webhooks,
+			Now:            serverNow,
+		},
+		Complexity: graph.Complexity(),
+	}))
+
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		Upgrader: websocket.Upgrader{
+			// Clients authenticate with a bearer token in the init payload,
+			// not cookies, so cross-origin connections are safe to accept.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		InitFunc: websocketInit,
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+
+	if limits.Introspection {
+		srv.Use(extension.Introspection{})
+	}
+	srv.Use(extension.FixedComplexityLimit(limits.MaxComplexity))
+	srv.Use(depthLimit{Max: limits.MaxDepth})
+	srv.SetErrorPresenter(graph.ErrorPresenter)
+
+	if pq.ManifestPath != "" || pq.Strict {
+		var ops map[string]string
+		if pq.ManifestPath != "" {
+			var err error
+			if ops, err = loadOperationManifest(pq.ManifestPath); err != nil {
+				log.Printf("failed to load operation manifest %s: %v", pq.ManifestPath, err)
+			}
+		}
+		if pq.Strict && len(ops) == 0 {
+			log.Println("Warning: strict persisted queries enabled with an empty allowlist; all operations will be rejected")
+		}
+		srv.Use(operationAllowlist{Operations: ops, Strict: pq.Strict})
+	}
+	cache := pq.Cache
+	if cache == nil {
+		cache = lru.New[string](pq.CacheSize)
+	}
+	srv.Use(extension.AutomaticPersistedQuery{Cache: cache})
+	return srv
+}
+
+// adminEmailsFromEnv reads ADMIN_EMAILS, a comma-separated list of the
+// email addresses of admin users.
+func adminEmailsFromEnv() []string {
+	var admins []string
+	for _, e := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			admins = append(admins, e)
+		}
+	}
+	return admins
+}
+
+// websocketInit authenticates a subscription connection from the
+// "Authorization" ("Bearer <token>") or "authToken" field of the
+// connection_init payload. A connection that is already authenticated by its
+// upgrade request headers is accepted as is.
+func websocketInit(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+	token := payload.GetString("authToken")
+	if authz := payload.Authorization(); authz != "" {
+		token = strings.TrimPrefix(authz, "Bearer ")
+	}
+	if token == "" {
+		if auth.ForContext(ctx) != "" {
+			return ctx, nil, nil
+		}
+		return nil, nil, errors.New("missing auth token")
+	}
+	claims, err := auth.ValidateToken(token)
+	if err != nil {
+		return nil, nil, errors.New("invalid auth token")
+	}
+	return context.WithValue(ctx, auth.UserIDKey, claims.UserID), nil, nil
+}
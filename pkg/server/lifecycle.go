@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/email"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/worker"
+)
+
+// DefaultShutdownTimeout bounds how long shutdown waits for in-flight
+// requests and the worker's current cycle.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// App runs the HTTP server and the background worker of a long-running
+// deployment until it is told to stop, then shuts them down in order and
+// closes the store.
+type App struct {
+	Server *http.Server
+	// Worker, if set, is started with the server and stopped after it.
+	Worker *worker.Worker
+	// Store, if set, is closed last.
+	Store           store.Store
+	ShutdownTimeout time.Duration
+}
+
+// NewApp returns an App serving Router on addr with a worker on St. Setup
+// must have been called first.
+func NewApp(addr string) *App {
+	a := &App{
+		Server:          &http.Server{Addr: addr, Handler: Router},
+		Store:           St,
+		ShutdownTimeout: DefaultShutdownTimeout,
+	}
+	if St != nil {
+		a.Worker = worker.NewWorker(St)
+		a.Worker.Now = serverNow
+		if Mailer != nil {
+			a.Worker.Mailer = Mailer
+		}
+		a.Worker.Mailer = email.WithSuppression(a.Worker.Mailer, St)
+		a.Worker.WebPush = WebPush
+		a.Worker.Push = Push
+		if Webhooks != nil {
+			a.Worker.Webhooks = Webhooks
+		}
+	}
+	return a
+}
+
+// Run listens on the server's address and serves until ctx is done, then
+// shuts down.
+func (a *App) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", a.Server.Addr)
+	if err != nil {
+		return err
+	}
+	return a.Serve(ctx, ln)
+}
+
+// Serve serves on ln until ctx is done or the server fails, then shuts down:
+// the server stops accepting connections and waits for in-flight requests,
+// subscriptions are closed, the worker finishes its current cycle and the
+// store is closed. The whole shutdown is bounded by ShutdownTimeout.
+func (a *App) Serve(ctx context.Context, ln net.Listener) error {
+	// Requests' contexts are cancelled on shutdown, which ends long-lived
+	// subscriptions that would otherwise hold their connections open
+	base, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	a.Server.BaseContext = func(net.Listener) context.Context { return base }
+	a.Server.RegisterOnShutdown(cancelBase)
+
+	if a.Worker != nil {
+		a.Worker.Start()
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- a.Server.Serve(ln) }()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down")
+	case err = <-serveErr:
+		log.Printf("Server failed, shutting down: %v", err)
+	}
+
+	timeout := a.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if shutdownErr := a.Server.Shutdown(shutdownCtx); shutdownErr != nil {
+		log.Printf("Error shutting down server: %v", shutdownErr)
+		a.Server.Close()
+	}
+	if a.Worker != nil {
+		if stopErr := a.Worker.Stop(shutdownCtx); stopErr != nil {
+			log.Printf("Error stopping worker: %v", stopErr)
+		}
+	}
+	if a.Store != nil {
+		if closeErr := a.Store.Close(); closeErr != nil {
+			log.Printf("Error closing store: %v", closeErr)
+		}
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/auth"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/ratelimit"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// GraphQLLimits bound the cost of GraphQL requests.
+type GraphQLLimits struct {
+	MaxDepth      int
+	MaxComplexity int
+	// Requests allowed per minute per authenticated user and per client IP.
+	// Zero disables the budget.
+	UserPerMinute int
+	IPPerMinute   int
+	// Introspection is disabled in production.
+	Introspection bool
+}
+
+// graphQLLimitsFromEnv reads GRAPHQL_MAX_DEPTH, GRAPHQL_MAX_COMPLEXITY,
+// RATE_LIMIT_USER_PER_MINUTE and RATE_LIMIT_IP_PER_MINUTE.
+func graphQLLimitsFromEnv() GraphQLLimits {
+	return GraphQLLimits{
+		MaxDepth:      envInt("GRAPHQL_MAX_DEPTH", 10),
+		MaxComplexity: envInt("GRAPHQL_MAX_COMPLEXITY", 1000),
+		UserPerMinute: envInt("RATE_LIMIT_USER_PER_MINUTE", 120),
+		IPPerMinute:   envInt("RATE_LIMIT_IP_PER_MINUTE", 300),
+		Introspection: !isProduction(),
+	}
+}
+
+// isProduction reports whether the server runs in production, either by
+// APP_ENV=production or on a Vercel production deployment.
+func isProduction() bool {
+	return os.Getenv("APP_ENV") == "production" || os.Getenv("VERCEL_ENV") == "production"
+}
+
+func envInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// depthLimit rejects operations whose selections nest deeper than Max.
+// Introspection fields are not counted, since the standard introspection
+// query is legitimately deep.
+type depthLimit struct {
+	Max int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = depthLimit{}
+
+func (d depthLimit) ExtensionName() string { return "DepthLimit" }
+
+func (d depthLimit) Validate(graphql.ExecutableSchema) error { return nil }
+
+func (d depthLimit) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	if opCtx.Operation == nil {
+		return nil
+	}
+	if depth := selectionDepth(opCtx.Operation.SelectionSet, map[string]bool{}); depth > d.Max {
+		return &gqlerror.Error{
+			Message:    fmt.Sprintf("operation has depth %d, which exceeds the limit of %d", depth, d.Max),
+			Extensions: map[string]any{"code": "DEPTH_LIMIT_EXCEEDED"},
+		}
+	}
+	return nil
+}
+
+func selectionDepth(set ast.SelectionSet, visiting map[string]bool) int {
+	max := 0
+	for _, sel := range set {
+		depth := 0
+		switch s := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			depth = 1 + selectionDepth(s.SelectionSet, visiting)
+		case *ast.InlineFragment:
+			depth = selectionDepth(s.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			// Cyclic fragments are rejected by validation; guard anyway.
+			if s.Definition == nil || visiting[s.Name] {
+				continue
+			}
+			visiting[s.Name] = true
+			depth = selectionDepth(s.Definition.SelectionSet, visiting)
+			delete(visiting, s.Name)
+		}
+		if depth > max {
+			max = depth
+		}
+	}
+	return max
+}
+
+// rateLimitMiddleware enforces per-user and per-IP request budgets. Rejected
+// requests get a 429 with a GraphQL error carrying extensions.code RATE_LIMITED.
+func rateLimitMiddleware(limits GraphQLLimits, next http.Handler) http.Handler {
+	perUser := ratelimit.PerMinute(limits.UserPerMinute)
+	perIP := ratelimit.PerMinute(limits.IPPerMinute)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ok, retryAfter := perIP.Allow(clientIP(r))
+		if ok {
+			if userID := auth.ForContext(r.Context()); userID != "" {
+				ok, retryAfter = perUser.Allow(userID)
+			}
+		}
+		if !ok {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]any{
+				"errors": gqlerror.List{{
+					Message:    "rate limit exceeded",
+					Extensions: map[string]any{"code": "RATE_LIMITED", "retryAfter": seconds},
+				}},
+				"data": nil,
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the caller's address. Forwarded headers are only trusted
+// behind a known proxy (Vercel, or TRUST_PROXY=1) since clients can forge them.
+func clientIP(r *http.Request) string {
+	if os.Getenv("VERCEL") == "1" || os.Getenv("TRUST_PROXY") == "1" {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+		if real := r.Header.Get("X-Real-Ip"); real != "" {
+			return real
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
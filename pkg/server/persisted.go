@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// PersistedQueryConfig controls automatic persisted queries (APQ) and the
+// operation allowlist.
+type PersistedQueryConfig struct {
+	// CacheSize is the size of the in-memory APQ cache, used unless Cache is set.
+	CacheSize int
+	Cache     graphql.Cache[string]
+	// ManifestPath points at the operation manifest generated by the app build.
+	ManifestPath string
+	// Strict rejects every operation that is not in the manifest.
+	Strict bool
+}
+
+// QueryCache overrides the in-memory APQ cache, e.g. with a Mongo-backed one
+// shared between instances. It is set by Setup when APQ_CACHE=mongo.
+var QueryCache graphql.Cache[string]
+
+func persistedQueryConfigFromEnv() PersistedQueryConfig {
+	return PersistedQueryConfig{
+		CacheSize:    envInt("APQ_CACHE_SIZE", 1000),
+		Cache:        QueryCache,
+		ManifestPath: os.Getenv("PERSISTED_QUERIES_MANIFEST"),
+		Strict:       os.Getenv("PERSISTED_QUERIES_STRICT") == "1",
+	}
+}
+
+// loadOperationManifest reads an operation manifest and returns its queries
+// keyed by SHA-256 hash. Both the Apollo persisted query manifest format and
+// a plain {"<sha256>": "<query>"} object are accepted. Hashes are recomputed
+// from the query text, so a manifest cannot map a hash to a different query.
+func loadOperationManifest(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var apollo struct {
+		Format     string `json:"format"`
+		Operations []struct {
+			Body string `json:"body"`
+		} `json:"operations"`
+	}
+	var queries []string
+	if err := json.Unmarshal(b, &apollo); err == nil && apollo.Format == "apollo-persisted-query-manifest" {
+		for _, op := range apollo.Operations {
+			queries = append(queries, op.Body)
+		}
+	} else {
+		var plain map[string]string
+		if err := json.Unmarshal(b, &plain); err != nil {
+			return nil, fmt.Errorf("unrecognised operation manifest: %w", err)
+		}
+		for _, q := range plain {
+			queries = append(queries, q)
+		}
+	}
+
+	ops := make(map[string]string, len(queries))
+	for _, q := range queries {
+		ops[queryHash(q)] = q
+	}
+	return ops, nil
+}
+
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// operationAllowlist resolves operations registered in the manifest by hash,
+// so clients never need to send their text, and in strict mode rejects any
+// operation that is not registered. It must run before the APQ extension.
+type operationAllowlist struct {
+	Operations map[string]string
+	Strict     bool
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = operationAllowlist{}
+
+func (a operationAllowlist) ExtensionName() string { return "OperationAllowlist" }
+
+func (a operationAllowlist) Validate(graphql.ExecutableSchema) error { return nil }
+
+func (a operationAllowlist) MutateOperationParameters(ctx context.Context, params *graphql.RawParams) *gqlerror.Error {
+	hash := persistedQueryHash(params)
+	if params.Query == "" && hash != "" {
+		if q, ok := a.Operations[hash]; ok {
+			// The manifest is authoritative, so skip the APQ cache entirely.
+			params.Query = q
+			delete(params.Extensions, "persistedQuery")
+			return nil
+		}
+	}
+	if !a.Strict {
+		return nil
+	}
+	if params.Query != "" {
+		hash = queryHash(params.Query)
+	}
+	if _, ok := a.Operations[hash]; !ok {
+		return &gqlerror.Error{
+			Message:    "operation is not in the allowlist",
+			Extensions: map[string]any{"code": "OPERATION_NOT_ALLOWED"},
+		}
+	}
+	return nil
+}
+
+// persistedQueryHash returns the sha256Hash of the request's persistedQuery
+// extension, if any.
+func persistedQueryHash(params *graphql.RawParams) string {
+	pq, ok := params.Extensions["persistedQuery"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	hash, _ := pq["sha256Hash"].(string)
+	return hash
+}
@@ -9,26 +9,53 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/RandithaK/StudyBuddy_Backend/graph"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/auth"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/email"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/i18n"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/pubsub"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/push"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/webhook"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/worker"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	Router *mux.Router
 	St     store.Store
 	Once   sync.Once
+	// Mailer sends the server's and worker's emails. If unset, the mailer
+	// configured by the environment is used.
+	Mailer email.Mailer
+	// WebPush sends the worker's Web Push notifications. If unset, the
+	// VAPID key configured by the environment is used, if any.
+	WebPush *push.WebPush
+	// Push sends the worker's mobile push notifications. If unset, the FCM
+	// service account configured by the environment is used, if any.
+	Push push.PushProvider
+	// Webhooks sends webhook deliveries. If unset, a webhook.Sender with the
+	// default client is used.
+	Webhooks *webhook.Sender
+	// Now returns the current time for the resolvers, the worker, webhook
+	// dispatch, access tokens and the store Setup creates. If unset,
+	// time.Now is used.
+	Now func() time.Time
 )
 
+// serverNow returns the current time by Now.
+func serverNow() time.Time {
+	if Now != nil {
+		return Now()
+	}
+	return time.Now()
+}
+
 // Setup initializes the database and router.
 // It is public (capitalized) so it can be called from main.go and api/index.go
 func Setup() {
@@ -41,13 +68,19 @@ func Setup() {
 		}
 	}
 
+	hasher, err := auth.PasswordHasherFromEnv()
+	if err != nil {
+		log.Printf("invalid password hasher config, using defaults: %v", err)
+	} else {
+		auth.SetPasswordHasher(hasher)
+	}
+
 	mongoURI := GetEnv("MONGO_URI", "")
 	if mongoURI == "" {
 		log.Println("Warning: MONGO_URI is empty")
 	}
 
 	ctx := context.Background()
-	var err error
 
 	if St == nil {
 		St, err = store.NewStore(ctx, mongoURI)
@@ -55,17 +88,37 @@ func Setup() {
 			log.Printf("failed to create store: %v", err)
 			return
 		}
+		switch s := St.(type) {
+		case *store.InMemoryStore:
+			s.Now = serverNow
+		case *store.MongoStore:
+			s.Now = serverNow
+		}
 
 		// Optional: Seed data (skip on Vercel production to avoid cold start delays)
 		if os.Getenv("VERCEL") != "1" {
 			SeedStore(St)
 		}
+
+		if ms, ok := St.(*store.MongoStore); ok && GetEnv("APQ_CACHE", "memory") == "mongo" {
+			QueryCache = ms.PersistedQueryCache()
+		}
+
+		// Publish changes made by resolvers and the worker to subscriptions
+		St = store.NewPublishingStore(St, pubsub.New())
 	}
 
-	// Start Worker (Only for local dev usually, or check flags)
-	if os.Getenv("VERCEL") != "1" {
-		w := worker.NewWorker(St)
-		w.Start()
+	if !email.UnsubscribeEnabled() {
+		log.Println("Warning: UNSUBSCRIBE_SECRET and JWT_SECRET are unset; unsubscribe links are disabled")
+	}
+	if Mailer == nil {
+		Mailer = mailerFromEnv()
+	}
+	if WebPush == nil {
+		WebPush = webPushFromEnv()
+	}
+	if Push == nil {
+		Push = pushProviderFromEnv()
 	}
 
 	if Router == nil {
@@ -74,7 +127,32 @@ func Setup() {
 }
 
 func SetupRouter(s store.Store) *mux.Router {
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{Store: s}}))
+	// Tokens are issued and expire by the server's clock too
+	auth.SetClock(serverNow)
+	ps, ok := s.(*store.PublishingStore)
+	if !ok {
+		ps = store.NewPublishingStore(s, pubsub.New())
+		s = ps
+	}
+	if ps.Dispatch == nil {
+		dispatcher := &webhook.Dispatcher{Store: ps.Store, Now: serverNow}
+		ps.Dispatch = dispatcher.Dispatch
+	}
+	mailer := Mailer
+	if mailer == nil {
+		mailer = mailerFromEnv()
+	}
+	mailer = email.WithSuppression(mailer, s)
+	webPush := WebPush
+	if webPush == nil {
+		webPush = webPushFromEnv()
+	}
+	var vapidPublicKey string
+	if webPush != nil {
+		vapidPublicKey = webPush.VAPID.PublicKey()
+	}
+	limits := graphQLLimitsFromEnv()
+	srv := newGraphQLServer(ps, mailer, vapidPublicKey, Webhooks, limits, persistedQueryConfigFromEnv())
 
 	r := mux.NewRouter()
 	r.Use(loggingMiddleware)
@@ -116,6 +194,9 @@ func SetupRouter(s store.Store) *mux.Router {
         `))
 	}).Methods(http.MethodGet)
 
+	// Public, as mail clients follow unsubscribe links without logging in
+	r.Handle("/unsubscribe", unsubscribeHandler(s)).Methods(http.MethodGet, http.MethodPost)
+
 	// Client-triggered email fallback (called by app background fetch)
 	r.HandleFunc("/api/notifications/check-email-fallback", func(w http.ResponseWriter, r *http.Request) {
 		// Get UserID from context (set by authMiddleware)
@@ -125,8 +206,9 @@ func SetupRouter(s store.Store) *mux.Router {
 			return
 		}
 
-		// Get unread notifications older than 1 hour for this user
-		notifications, err := s.GetUnreadNotificationsOlderThanForUser(userID, "1h")
+		// Get this user's unread notifications; their preferences decide
+		// which are due to be emailed
+		notifications, err := s.GetUnreadNotificationsOlderThanForUser(userID, "0s")
 		if err != nil {
 			log.Printf("Error getting unread notifications for user %s: %v", userID, err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -139,19 +221,20 @@ func SetupRouter(s store.Store) *mux.Router {
 			return
 		}
 
-		if !user.IsVerified {
-			// Just mark as emailed to avoid repeated checks
-			for _, n := range notifications {
-				s.MarkNotificationAsEmailed(n.ID)
-			}
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("User not verified, skipped emails"))
-			return
-		}
-
 		count := 0
+		now := serverNow()
 		for _, n := range notifications {
-			err = email.SendNotificationEmail(user.Email, "You have an unread notification", n.Message)
+			send, skip := worker.EmailFallback(user, n, now)
+			if skip {
+				// Just mark as emailed to avoid repeated checks
+				s.MarkNotificationAsEmailed(n.ID)
+				continue
+			}
+			if !send {
+				continue
+			}
+			l := i18n.For(user)
+			err = email.SendNotificationEmail(r.Context(), mailer, user.Email, l.Locale, l.Notification(n))
 			if err != nil {
 				log.Printf("Error sending email to %s: %v", user.Email, err)
 				continue
@@ -164,6 +247,22 @@ func SetupRouter(s store.Store) *mux.Router {
 		fmt.Fprintf(w, "Processed %d notifications", count)
 	}).Methods(http.MethodPost)
 
+	// Scheduler-triggered worker cycle, for deployments without the
+	// background worker
+	cronWorker := worker.NewWorker(s)
+	cronWorker.Now = serverNow
+	cronWorker.Mailer = mailer
+	cronWorker.WebPush = webPush
+	cronWorker.Push = Push
+	if cronWorker.Push == nil {
+		cronWorker.Push = pushProviderFromEnv()
+	}
+	if Webhooks != nil {
+		cronWorker.Webhooks = Webhooks
+	}
+	cron := cronTickHandler(cronWorker, os.Getenv("CRON_SECRET"), cronBudgetFromEnv())
+	r.Handle("/api/cron/tick", cron).Methods(http.MethodGet, http.MethodPost)
+
 	// Refresh Token Endpoint
 	r.HandleFunc("/refresh-token", func(w http.ResponseWriter, r *http.Request) {
 		type RefreshRequest struct {
@@ -223,15 +322,50 @@ func SetupRouter(s store.Store) *mux.Router {
 
 	// GraphQL playground and handlers
 	r.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	r.Handle("/query", srv)
+	gql := rateLimitMiddleware(limits, graph.LoaderMiddleware(s, srv))
+	r.Handle("/query", gql)
 	// Support Vercel's /api/* route prefix in production deployments
 	// (e.g. https://<host>/api/query). This ensures requests made to
 	// '/api/query' are handled properly when the Vercel router passes through the path.
-	r.Handle("/api/query", srv)
+	r.Handle("/api/query", gql)
 
 	return r
 }
 
+// mailerFromEnv returns the mailer configured by the environment, or one
+// that only logs emails if the configuration is invalid.
+func mailerFromEnv() email.Mailer {
+	m, err := email.MailerFromEnv()
+	if err != nil {
+		log.Printf("invalid email config, logging emails instead: %v", err)
+		return email.LogMailer{}
+	}
+	return m
+}
+
+func webPushFromEnv() *push.WebPush {
+	p, err := push.WebPushFromEnv()
+	if err != nil {
+		log.Printf("invalid VAPID config, Web Push is disabled: %v", err)
+		return nil
+	}
+	return p
+}
+
+// pushProviderFromEnv returns the FCM provider configured by the
+// environment, or nil, not a nil *push.FCM, if there is none.
+func pushProviderFromEnv() push.PushProvider {
+	f, err := push.FCMFromEnv()
+	if err != nil {
+		log.Printf("invalid FCM config, mobile push is disabled: %v", err)
+		return nil
+	}
+	if f == nil {
+		return nil
+	}
+	return f
+}
+
 func GetEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -269,12 +403,12 @@ func authMiddleware(next http.Handler) http.Handler {
 
 func SeedStore(s store.Store) {
 	// (Keep your seed logic here exactly as it was)
-	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	hash, _ := auth.HashPassword("password")
 	user := models.User{
 		ID:       "test-user-id",
 		Name:     "Test User",
 		Email:    "test@example.com",
-		Password: string(hash),
+		Password: hash,
 	}
 	if _, exists := s.GetUserByEmail(user.Email); !exists {
 		s.CreateUser(user)
@@ -301,8 +435,7 @@ func SeedStore(s store.Store) {
 			Description: "Complete problem set 1",
 			CourseID:    "course-1",
 			UserID:      userID,
-			DueDate:     "2025-12-10",
-			DueTime:     "23:59",
+			DueAt:       time.Date(2025, 12, 10, 23, 59, 0, 0, time.UTC),
 			Completed:   false,
 			HasReminder: true,
 		},
@@ -312,8 +445,7 @@ func SeedStore(s store.Store) {
 			Description: "Complete problem set 2",
 			CourseID:    "course-1",
 			UserID:      userID,
-			DueDate:     "2025-12-12",
-			DueTime:     "23:59",
+			DueAt:       time.Date(2025, 12, 12, 23, 59, 0, 0, time.UTC),
 			Completed:   true,
 			HasReminder: false,
 		},
@@ -323,8 +455,7 @@ func SeedStore(s store.Store) {
 			Description: "Write lab report",
 			CourseID:    "course-2",
 			UserID:      userID,
-			DueDate:     "2025-12-08",
-			DueTime:     "17:00",
+			DueAt:       time.Date(2025, 12, 8, 17, 0, 0, 0, time.UTC),
 			Completed:   false,
 			HasReminder: true,
 		},
@@ -334,8 +465,7 @@ func SeedStore(s store.Store) {
 			Description: "Write essay",
 			CourseID:    "course-2",
 			UserID:      userID,
-			DueDate:     "2025-12-15",
-			DueTime:     "23:59",
+			DueAt:       time.Date(2025, 12, 15, 23, 59, 0, 0, time.UTC),
 			Completed:   false,
 			HasReminder: false,
 		},
@@ -345,8 +475,7 @@ func SeedStore(s store.Store) {
 			Description: "Practice derivatives",
 			CourseID:    "course-3",
 			UserID:      userID,
-			DueDate:     "2025-12-05",
-			DueTime:     "14:00",
+			DueAt:       time.Date(2025, 12, 5, 14, 0, 0, 0, time.UTC),
 			Completed:   false,
 			HasReminder: true,
 		},
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/email"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+)
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<html>
+    <head><title>{{.Title}}</title></head>
+    <body style="font-family: sans-serif; text-align: center; padding: 50px;">
+        <h1>{{.Title}}</h1>
+        <p>{{.Message}}</p>
+        {{if .Action}}<form method="post">
+            <input type="hidden" name="action" value="{{.Action}}">
+            <button type="submit">{{.Button}}</button>
+        </form>{{end}}
+    </body>
+</html>
+`))
+
+type unsubscribePageData struct {
+	Title, Message string
+	Action, Button string
+}
+
+var categoryNames = map[string]string{
+	email.CategoryNotifications: "notification emails",
+	email.CategoryDigest:        "digest emails",
+}
+
+// unsubscribeHandler serves the links in unsubscribe emails. GET shows a
+// page confirming the unsubscribe, so that link scanners fetching the link
+// do not unsubscribe anyone. POST unsubscribes, either from that page or
+// as an RFC 8058 one-click unsubscribe, or resubscribes with action
+// "resubscribe".
+func unsubscribeHandler(s store.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !email.UnsubscribeEnabled() {
+			http.NotFound(w, r)
+			return
+		}
+		address, category, err := email.ParseUnsubscribeToken(r.URL.Query().Get("token"))
+		if err != nil {
+			http.Error(w, "Invalid unsubscribe link", http.StatusBadRequest)
+			return
+		}
+		name := categoryNames[category]
+
+		page := unsubscribePageData{
+			Title:   "Unsubscribe",
+			Message: "Stop sending " + name + " to " + address + "?",
+			Action:  "unsubscribe",
+			Button:  "Unsubscribe",
+		}
+		if r.Method == http.MethodPost {
+			if r.PostFormValue("action") == "resubscribe" {
+				err = s.RemoveSuppression(address, category)
+				if err == store.ErrNotFound {
+					err = nil
+				}
+				page = unsubscribePageData{
+					Title:   "Resubscribed",
+					Message: address + " will receive " + name + " again.",
+				}
+			} else {
+				_, err = s.AddSuppression(models.Suppression{Email: address, Category: category, Reason: models.SuppressionUnsubscribed})
+				page = unsubscribePageData{
+					Title:   "Unsubscribed",
+					Message: address + " will no longer receive " + name + ".",
+					Action:  "resubscribe",
+					Button:  "Resubscribe",
+				}
+			}
+			if err != nil {
+				log.Printf("Error updating suppression of %s: %v", address, err)
+				http.Error(w, "Failed to update your subscription", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		unsubscribePage.Execute(w, page)
+	})
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/pubsub"
+)
+
+// PublishingStore wraps a Store and publishes task, event and notification
+// changes to a pubsub.Broker after they are written successfully.
+type PublishingStore struct {
+	Store
+	broker *pubsub.Broker
+	// Dispatch, if set, is also called with each change webhooks can
+	// subscribe to: its user, its webhook event type and the changed task,
+	// event or notification.
+	Dispatch func(userID, event string, data any)
+}
+
+func NewPublishingStore(s Store, b *pubsub.Broker) *PublishingStore {
+	return &PublishingStore{Store: s, broker: b}
+}
+
+// Broker returns the broker changes are published to.
+func (p *PublishingStore) Broker() *pubsub.Broker {
+	return p.broker
+}
+
+func (p *PublishingStore) CreateTask(t models.Task) models.Task {
+	created := p.Store.CreateTask(t)
+	p.publish(pubsub.TopicTask, created.UserID, pubsub.ActionCreated, created)
+	p.dispatch(created.UserID, models.WebhookTaskCreated, created)
+	return created
+}
+
+func (p *PublishingStore) UpdateTask(id string, t models.Task) (models.Task, error) {
+	var wasCompleted bool
+	if p.Dispatch != nil {
+		existing, err := p.Store.GetTask(id)
+		wasCompleted = err == nil && existing.Completed
+	}
+	updated, err := p.Store.UpdateTask(id, t)
+	if err == nil {
+		p.publish(pubsub.TopicTask, updated.UserID, pubsub.ActionUpdated, updated)
+		if updated.Completed && !wasCompleted {
+			p.dispatch(updated.UserID, models.WebhookTaskCompleted, updated)
+		}
+	}
+	return updated, err
+}
+
+func (p *PublishingStore) DeleteTask(id string) error {
+	existing, err := p.Store.GetTask(id)
+	if err != nil {
+		return err
+	}
+	if err := p.Store.DeleteTask(id); err != nil {
+		return err
+	}
+	p.publish(pubsub.TopicTask, existing.UserID, pubsub.ActionDeleted, existing)
+	return nil
+}
+
+func (p *PublishingStore) CreateEvent(e models.Event) models.Event {
+	created := p.Store.CreateEvent(e)
+	p.publish(pubsub.TopicEvent, created.UserID, pubsub.ActionCreated, created)
+	return created
+}
+
+func (p *PublishingStore) UpdateEvent(id string, e models.Event) (models.Event, error) {
+	updated, err := p.Store.UpdateEvent(id, e)
+	if err == nil {
+		p.publish(pubsub.TopicEvent, updated.UserID, pubsub.ActionUpdated, updated)
+		p.dispatch(updated.UserID, models.WebhookEventUpdated, updated)
+	}
+	return updated, err
+}
+
+func (p *PublishingStore) DeleteEvent(id string) error {
+	existing, err := p.Store.GetEvent(id)
+	if err != nil {
+		return err
+	}
+	if err := p.Store.DeleteEvent(id); err != nil {
+		return err
+	}
+	p.publish(pubsub.TopicEvent, existing.UserID, pubsub.ActionDeleted, existing)
+	return nil
+}
+
+func (p *PublishingStore) CreateNotification(n models.Notification) models.Notification {
+	created := p.Store.CreateNotification(n)
+	p.publish(pubsub.TopicNotification, created.UserID, pubsub.ActionCreated, created)
+	p.dispatch(created.UserID, models.WebhookNotificationCreated, created)
+	return created
+}
+
+func (p *PublishingStore) CreateNotificationOnce(n models.Notification) (models.Notification, bool, error) {
+	n, created, err := p.Store.CreateNotificationOnce(n)
+	if created {
+		p.publish(pubsub.TopicNotification, n.UserID, pubsub.ActionCreated, n)
+		p.dispatch(n.UserID, models.WebhookNotificationCreated, n)
+	}
+	return n, created, err
+}
+
+func (p *PublishingStore) publish(topic, userID, action string, payload any) {
+	p.broker.Publish(pubsub.Event{Topic: topic, UserID: userID, Action: action, Payload: payload})
+}
+
+func (p *PublishingStore) dispatch(userID, event string, data any) {
+	if p.Dispatch != nil {
+		p.Dispatch(userID, event, data)
+	}
+}
@@ -0,0 +1,193 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageInfo describes a page of a cursor-paginated list.
+type PageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	StartCursor     string
+	EndCursor       string
+}
+
+type TaskSortField string
+
+const (
+	TaskSortDueDate   TaskSortField = "DUE_DATE"
+	TaskSortCreatedAt TaskSortField = "CREATED_AT"
+	TaskSortTitle     TaskSortField = "TITLE"
+)
+
+// TaskFilter narrows a task listing. Nil/zero fields are ignored.
+// Tasks due in [DueFrom, DueTo) match.
+type TaskFilter struct {
+	CourseID    *string
+	Completed   *bool
+	DueFrom     time.Time
+	DueTo       time.Time
+	HasReminder *bool
+	Overdue     *bool
+}
+
+// TaskQuery selects one page of a user's tasks.
+type TaskQuery struct {
+	Filter     TaskFilter
+	SortBy     TaskSortField
+	Descending bool
+	First      int
+	After      string
+	// Now is the reference time for the Overdue filter.
+	Now time.Time
+}
+
+type TaskEdge struct {
+	Cursor string
+	Task   models.Task
+}
+
+type TaskPage struct {
+	Edges      []TaskEdge
+	PageInfo   PageInfo
+	TotalCount int
+}
+
+// TaskCounts are the number of tasks in a course and how many are completed.
+type TaskCounts struct {
+	Total     int
+	Completed int
+}
+
+// EventFilter narrows an event listing to events starting in [From, To).
+// Nil/zero fields are ignored.
+type EventFilter struct {
+	From     time.Time
+	To       time.Time
+	Type     *string
+	CourseID *string
+}
+
+// NotificationQuery selects one page of a user's notifications, newest first.
+type NotificationQuery struct {
+	UnreadOnly bool
+	First      int
+	After      string
+}
+
+type NotificationEdge struct {
+	Cursor       string
+	Notification models.Notification
+}
+
+type NotificationPage struct {
+	Edges      []NotificationEdge
+	PageInfo   PageInfo
+	TotalCount int
+}
+
+const notificationSort = "CREATED_AT_DESC"
+
+// pageSize clamps a requested page size to [1, MaxPageSize].
+func pageSize(first int) int {
+	if first <= 0 {
+		return DefaultPageSize
+	}
+	if first > MaxPageSize {
+		return MaxPageSize
+	}
+	return first
+}
+
+// cursor is the decoded form of the opaque pagination cursor: the values of
+// the sort keys of the last item seen, plus its ID as a tie-breaker.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	ID     string   `json:"id"`
+}
+
+func encodeCursor(sort string, values []string, id string) string {
+	b, _ := json.Marshal(cursor{Sort: sort, Values: values, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string, sort string, nValues int) (cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	if c.Sort != sort || len(c.Values) != nValues {
+		return cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// sortKeyLayout formats timestamps in sort keys. It is fixed width, so keys
+// compare lexically in chronological order.
+const sortKeyLayout = "2006-01-02T15:04:05.000000000Z"
+
+func timeSortKey(t time.Time) string {
+	return t.UTC().Format(sortKeyLayout)
+}
+
+// timeSortFields are the stored fields whose sort keys are timestamps.
+var timeSortFields = map[string]bool{"dueAt": true, "createdAt": true}
+
+// taskSortFields returns the stored field names a task sort orders by, in priority order.
+func taskSortFields(f TaskSortField) []string {
+	switch f {
+	case TaskSortCreatedAt:
+		return []string{"createdAt"}
+	case TaskSortTitle:
+		return []string{"title"}
+	default:
+		return []string{"dueAt"}
+	}
+}
+
+func taskSortValues(f TaskSortField, t models.Task) []string {
+	switch f {
+	case TaskSortCreatedAt:
+		return []string{timeSortKey(t.CreatedAt)}
+	case TaskSortTitle:
+		return []string{t.Title}
+	default:
+		return []string{timeSortKey(t.DueAt)}
+	}
+}
+
+func normalizeTaskSort(f TaskSortField) TaskSortField {
+	switch f {
+	case TaskSortCreatedAt, TaskSortTitle:
+		return f
+	default:
+		return TaskSortDueDate
+	}
+}
+
+// isOverdue reports whether an incomplete task was due before now.
+func isOverdue(t models.Task, now time.Time) bool {
+	return !t.Completed && t.DueAt.Before(now)
+}
+
+// inRange reports whether t lies in [from, to), treating zero bounds as open.
+func inRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// persistedQueryTTL is how long an automatic persisted query is kept after it
+// was registered; clients transparently re-register expired hashes.
+const persistedQueryTTL = 30 * 24 * time.Hour
+
+// MongoQueryCache stores automatic persisted queries in the
+// "persisted_queries" collection so they are shared by every instance. It
+// satisfies gqlgen's graphql.Cache[string].
+type MongoQueryCache struct {
+	col *mongo.Collection
+}
+
+// PersistedQueryCache returns a query cache backed by this store's database.
+func (m *MongoStore) PersistedQueryCache() *MongoQueryCache {
+	return &MongoQueryCache{col: m.db.Collection("persisted_queries")}
+}
+
+func (c *MongoQueryCache) Get(ctx context.Context, hash string) (string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var doc struct {
+		Query string `bson:"query"`
+	}
+	if err := c.col.FindOne(ctx, bson.M{"_id": hash}).Decode(&doc); err != nil {
+		return "", false
+	}
+	return doc.Query, true
+}
+
+func (c *MongoQueryCache) Add(ctx context.Context, hash string, query string) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := c.col.UpdateOne(ctx,
+		bson.M{"_id": hash},
+		bson.M{"$set": bson.M{"query": query, "createdAt": time.Now()}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		log.Printf("failed to store persisted query %s: %v", hash, err)
+	}
+}
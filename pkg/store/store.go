@@ -2,30 +2,72 @@ package store
 
 import (
 	"errors"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/google/uuid"
 )
 
 var (
 	ErrNotFound = errors.New("not found")
+	// ErrJobLost is returned when finishing a job whose claim has expired
+	// and which may since have been claimed by another worker.
+	ErrJobLost = errors.New("job is no longer claimed")
 )
 
 // In-memory thread-safe store
 type InMemoryStore struct {
-	mu      sync.RWMutex
-	tasks   map[string]models.Task
-	courses map[string]models.Course
-	events  map[string]models.Event
-	users   map[string]models.User
+	mu            sync.RWMutex
+	tasks         map[string]models.Task
+	courses       map[string]models.Course
+	events        map[string]models.Event
+	users         map[string]models.User
+	notifications map[string]models.Notification
+	jobs          map[string]models.Job
+	leases        map[string]lease
+	suppressions  map[suppressionKey]models.Suppression
+	pushSubs      map[string]models.PushSubscription
+	deviceTokens  map[string]models.DeviceToken
+	webhooks      map[string]models.Webhook
+	deliveries    map[string][]models.WebhookDelivery
+
+	// Now returns the current time, used for timestamps, due windows and
+	// leases. Tests may replace it with a fake clock before use.
+	Now func() time.Time
+}
+
+// lease is a named lock held by holder until it expires.
+type lease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+type suppressionKey struct{ email, category string }
+
+// normalizeEmail returns the form addresses are suppressed under, so a
+// suppression applies however the address is capitalized.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 func NewInMemoryStore() *InMemoryStore {
 	return &InMemoryStore{
-		tasks:   make(map[string]models.Task),
-		courses: make(map[string]models.Course),
-		events:  make(map[string]models.Event),
-		users:   make(map[string]models.User),
+		tasks:         make(map[string]models.Task),
+		courses:       make(map[string]models.Course),
+		events:        make(map[string]models.Event),
+		users:         make(map[string]models.User),
+		notifications: make(map[string]models.Notification),
+		jobs:          make(map[string]models.Job),
+		leases:        make(map[string]lease),
+		suppressions:  make(map[suppressionKey]models.Suppression),
+		pushSubs:      make(map[string]models.PushSubscription),
+		deviceTokens:  make(map[string]models.DeviceToken),
+		webhooks:      make(map[string]models.Webhook),
+		deliveries:    make(map[string][]models.WebhookDelivery),
+		Now:           time.Now,
 	}
 }
 
@@ -39,9 +81,114 @@ func (s *InMemoryStore) GetTasks(userID string) []models.Task {
 			res = append(res, t)
 		}
 	}
+	sortTasks(res, TaskSortDueDate, false)
 	return res
 }
 
+func (s *InMemoryStore) ListTasks(userID string, q TaskQuery) (TaskPage, error) {
+	q.SortBy = normalizeTaskSort(q.SortBy)
+	fields := taskSortFields(q.SortBy)
+	var after *cursor
+	if q.After != "" {
+		c, err := decodeCursor(q.After, string(q.SortBy), len(fields))
+		if err != nil {
+			return TaskPage{}, err
+		}
+		after = &c
+	}
+
+	s.mu.RLock()
+	matched := make([]models.Task, 0)
+	for _, t := range s.tasks {
+		if t.UserID == userID && matchesTaskFilter(t, q.Filter, q.Now) {
+			matched = append(matched, t)
+		}
+	}
+	s.mu.RUnlock()
+
+	sortTasks(matched, q.SortBy, q.Descending)
+	page := TaskPage{TotalCount: len(matched)}
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			c := compareKeys(taskSortValues(q.SortBy, matched[i]), matched[i].ID, after.Values, after.ID)
+			if q.Descending {
+				return c < 0
+			}
+			return c > 0
+		})
+		page.PageInfo.HasPreviousPage = true
+	}
+
+	limit := pageSize(q.First)
+	end := start + limit
+	if end > len(matched) {
+		end = len(matched)
+	} else {
+		page.PageInfo.HasNextPage = end < len(matched)
+	}
+	for _, t := range matched[start:end] {
+		page.Edges = append(page.Edges, TaskEdge{
+			Cursor: encodeCursor(string(q.SortBy), taskSortValues(q.SortBy, t), t.ID),
+			Task:   t,
+		})
+	}
+	if n := len(page.Edges); n > 0 {
+		page.PageInfo.StartCursor = page.Edges[0].Cursor
+		page.PageInfo.EndCursor = page.Edges[n-1].Cursor
+	}
+	return page, nil
+}
+
+func matchesTaskFilter(t models.Task, f TaskFilter, now time.Time) bool {
+	if f.CourseID != nil && t.CourseID != *f.CourseID {
+		return false
+	}
+	if f.Completed != nil && t.Completed != *f.Completed {
+		return false
+	}
+	if !inRange(t.DueAt, f.DueFrom, f.DueTo) {
+		return false
+	}
+	if f.HasReminder != nil && t.HasReminder != *f.HasReminder {
+		return false
+	}
+	if f.Overdue != nil && isOverdue(t, now) != *f.Overdue {
+		return false
+	}
+	return true
+}
+
+func sortTasks(tasks []models.Task, by TaskSortField, desc bool) {
+	sort.Slice(tasks, func(i, j int) bool {
+		c := compareKeys(taskSortValues(by, tasks[i]), tasks[i].ID, taskSortValues(by, tasks[j]), tasks[j].ID)
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// compareKeys orders two items by their sort values, then by ID.
+func compareKeys(a []string, aID string, b []string, bID string) int {
+	for i := range a {
+		if a[i] < b[i] {
+			return -1
+		}
+		if a[i] > b[i] {
+			return 1
+		}
+	}
+	switch {
+	case aID < bID:
+		return -1
+	case aID > bID:
+		return 1
+	}
+	return 0
+}
+
 func (s *InMemoryStore) GetTask(id string) (models.Task, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -54,6 +201,12 @@ func (s *InMemoryStore) GetTask(id string) (models.Task, error) {
 func (s *InMemoryStore) CreateTask(t models.Task) models.Task {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = s.Now()
+	}
 	s.tasks[t.ID] = t
 	return t
 }
@@ -86,19 +239,6 @@ func (s *InMemoryStore) GetCourses(userID string) []models.Course {
 	res := make([]models.Course, 0, len(s.courses))
 	for _, c := range s.courses {
 		if c.UserID == userID {
-			// Calculate totalTasks and completedTasks for this course
-			totalTasks := 0
-			completedTasks := 0
-			for _, t := range s.tasks {
-				if t.UserID == userID && t.CourseID == c.ID {
-					totalTasks++
-					if t.Completed {
-						completedTasks++
-					}
-				}
-			}
-			c.TotalTasks = totalTasks
-			c.CompletedTasks = completedTasks
 			res = append(res, c)
 		}
 	}
@@ -114,9 +254,45 @@ func (s *InMemoryStore) GetCourse(id string) (models.Course, error) {
 	return models.Course{}, ErrNotFound
 }
 
+func (s *InMemoryStore) GetCoursesByIDs(ids []string) ([]models.Course, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]models.Course, 0, len(ids))
+	for _, id := range ids {
+		if c, ok := s.courses[id]; ok {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (s *InMemoryStore) CountTasksByCourse(courseIDs []string) (map[string]TaskCounts, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make(map[string]TaskCounts, len(courseIDs))
+	for _, id := range courseIDs {
+		res[id] = TaskCounts{}
+	}
+	for _, t := range s.tasks {
+		counts, ok := res[t.CourseID]
+		if !ok {
+			continue
+		}
+		counts.Total++
+		if t.Completed {
+			counts.Completed++
+		}
+		res[t.CourseID] = counts
+	}
+	return res, nil
+}
+
 func (s *InMemoryStore) CreateCourse(c models.Course) models.Course {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
 	s.courses[c.ID] = c
 	return c
 }
@@ -131,16 +307,80 @@ func (s *InMemoryStore) GetEvents(userID string) []models.Event {
 			res = append(res, e)
 		}
 	}
+	sortEvents(res)
 	return res
 }
 
+func (s *InMemoryStore) ListEvents(userID string, f EventFilter) ([]models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]models.Event, 0)
+	for _, e := range s.events {
+		if e.UserID != userID {
+			continue
+		}
+		if !inRange(e.StartsAt, f.From, f.To) {
+			continue
+		}
+		if f.Type != nil && e.Type != *f.Type {
+			continue
+		}
+		if f.CourseID != nil && e.CourseID != *f.CourseID {
+			continue
+		}
+		res = append(res, e)
+	}
+	sortEvents(res)
+	return res, nil
+}
+
+// sortEvents orders events chronologically.
+func sortEvents(es []models.Event) {
+	sort.Slice(es, func(i, j int) bool {
+		return compareKeys([]string{timeSortKey(es[i].StartsAt)}, es[i].ID, []string{timeSortKey(es[j].StartsAt)}, es[j].ID) < 0
+	})
+}
+
 func (s *InMemoryStore) CreateEvent(e models.Event) models.Event {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
 	s.events[e.ID] = e
 	return e
 }
 
+func (s *InMemoryStore) GetEvent(id string) (models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if e, ok := s.events[id]; ok {
+		return e, nil
+	}
+	return models.Event{}, ErrNotFound
+}
+
+func (s *InMemoryStore) UpdateEvent(id string, e models.Event) (models.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.events[id]; !ok {
+		return models.Event{}, ErrNotFound
+	}
+	e.ID = id
+	s.events[id] = e
+	return e, nil
+}
+
+func (s *InMemoryStore) DeleteEvent(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.events[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.events, id)
+	return nil
+}
+
 // User operations
 func (s *InMemoryStore) GetUser(id string) (models.User, error) {
 	s.mu.RLock()
@@ -203,6 +443,9 @@ func (s *InMemoryStore) UpdateUser(id string, u models.User) (models.User, error
 	if u.RefreshToken != "" {
 		existing.RefreshToken = u.RefreshToken
 	}
+	if u.TimeZone != "" {
+		existing.TimeZone = u.TimeZone
+	}
 
 	s.users[id] = existing
 	return existing, nil
@@ -223,6 +466,44 @@ func (s *InMemoryStore) UpdateUserPassword(id string, hashedPassword string) (mo
 	return existing, nil
 }
 
+func (s *InMemoryStore) UpdateUserPreferences(id string, p models.Preferences) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.users[id]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	existing.Preferences = &p
+	s.users[id] = existing
+	return existing, nil
+}
+
+// GetUsersWithDigest returns the users who have a digest scheduled.
+func (s *InMemoryStore) GetUsersWithDigest() ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]models.User, 0)
+	for _, u := range s.users {
+		if u.Preferences != nil && u.Preferences.Digest != nil {
+			res = append(res, u)
+		}
+	}
+	return res, nil
+}
+
+// MarkDigestSent records that the digest scheduled for at has been sent.
+func (s *InMemoryStore) MarkDigestSent(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	existing.DigestSentAt = &at
+	s.users[id] = existing
+	return nil
+}
+
 func (s *InMemoryStore) MarkUserVerified(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -236,39 +517,669 @@ func (s *InMemoryStore) MarkUserVerified(id string) error {
 	return nil
 }
 
-// Notifications (Stub implementation for InMemoryStore)
-func (s *InMemoryStore) GetNotifications(userID string) []models.Notification {
-	return []models.Notification{}
+// Notifications
+func (s *InMemoryStore) GetNotifications(userID string, unreadOnly bool) []models.Notification {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]models.Notification, 0)
+	for _, n := range s.notifications {
+		if n.UserID == userID && (!unreadOnly || !n.Read) {
+			res = append(res, n)
+		}
+	}
+	sortNotifications(res)
+	return res
+}
+
+func (s *InMemoryStore) ListNotifications(userID string, q NotificationQuery) (NotificationPage, error) {
+	var after *cursor
+	if q.After != "" {
+		c, err := decodeCursor(q.After, notificationSort, 1)
+		if err != nil {
+			return NotificationPage{}, err
+		}
+		after = &c
+	}
+
+	matched := s.GetNotifications(userID, q.UnreadOnly)
+	page := NotificationPage{TotalCount: len(matched)}
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			return compareKeys([]string{timeSortKey(matched[i].CreatedAt)}, matched[i].ID, after.Values, after.ID) < 0
+		})
+		page.PageInfo.HasPreviousPage = true
+	}
+	end := start + pageSize(q.First)
+	if end > len(matched) {
+		end = len(matched)
+	} else {
+		page.PageInfo.HasNextPage = end < len(matched)
+	}
+	for _, n := range matched[start:end] {
+		page.Edges = append(page.Edges, NotificationEdge{
+			Cursor:       encodeCursor(notificationSort, []string{timeSortKey(n.CreatedAt)}, n.ID),
+			Notification: n,
+		})
+	}
+	if n := len(page.Edges); n > 0 {
+		page.PageInfo.StartCursor = page.Edges[0].Cursor
+		page.PageInfo.EndCursor = page.Edges[n-1].Cursor
+	}
+	return page, nil
+}
+
+// sortNotifications orders notifications newest first.
+func sortNotifications(ns []models.Notification) {
+	sort.Slice(ns, func(i, j int) bool {
+		return compareKeys([]string{timeSortKey(ns[i].CreatedAt)}, ns[i].ID, []string{timeSortKey(ns[j].CreatedAt)}, ns[j].ID) > 0
+	})
+}
+
+func (s *InMemoryStore) GetNotification(id string) (models.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if n, ok := s.notifications[id]; ok {
+		return n, nil
+	}
+	return models.Notification{}, ErrNotFound
+}
+
+func (s *InMemoryStore) GetNotificationByDedupKey(key string) (models.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, n := range s.notifications {
+		if n.DedupKey == key {
+			return n, nil
+		}
+	}
+	return models.Notification{}, ErrNotFound
 }
 
 func (s *InMemoryStore) GetNotificationByReferenceID(refID string, nType string) (models.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, n := range s.notifications {
+		if n.ReferenceID == refID && n.Type == nType {
+			return n, nil
+		}
+	}
 	return models.Notification{}, ErrNotFound
 }
 
 func (s *InMemoryStore) CreateNotification(n models.Notification) models.Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.insertNotification(n)
+}
+
+// insertNotification stores n. The caller must hold s.mu.
+func (s *InMemoryStore) insertNotification(n models.Notification) models.Notification {
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = s.Now()
+	}
+	s.notifications[n.ID] = n
 	return n
 }
 
+// CreateNotificationOnce creates n unless a notification with the same
+// reference, type and dedup key exists, in which case that notification is
+// returned and the bool result is false.
+func (s *InMemoryStore) CreateNotificationOnce(n models.Notification) (models.Notification, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.notifications {
+		if existing.DedupKey != "" && existing.DedupKey == n.DedupKey &&
+			existing.ReferenceID == n.ReferenceID && existing.Type == n.Type {
+			return existing, false, nil
+		}
+	}
+	return s.insertNotification(n), true, nil
+}
+
 func (s *InMemoryStore) MarkNotificationAsRead(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.notifications[id]
+	if !ok {
+		return ErrNotFound
+	}
+	n.Read = true
+	s.notifications[id] = n
+	return nil
+}
+
+// SnoozeNotification marks a notification as read until it is sent again
+// at until.
+func (s *InMemoryStore) SnoozeNotification(id string, until time.Time) (models.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.notifications[id]
+	if !ok {
+		return models.Notification{}, ErrNotFound
+	}
+	n.Read = true
+	n.SnoozedUntil = &until
+	s.notifications[id] = n
+	return n, nil
+}
+
+// GetNotificationsSnoozedUntil returns notifications whose snooze has ended
+// by t.
+func (s *InMemoryStore) GetNotificationsSnoozedUntil(t time.Time) ([]models.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]models.Notification, 0)
+	for _, n := range s.notifications {
+		if n.SnoozedUntil != nil && !n.SnoozedUntil.After(t) {
+			res = append(res, n)
+		}
+	}
+	return res, nil
+}
+
+func (s *InMemoryStore) ClearNotificationSnooze(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.notifications[id]
+	if !ok {
+		return ErrNotFound
+	}
+	n.SnoozedUntil = nil
+	s.notifications[id] = n
 	return nil
 }
 
 func (s *InMemoryStore) GetUnreadNotificationsOlderThan(duration string) ([]models.Notification, error) {
-	return []models.Notification{}, nil
+	return s.unreadNotificationsOlderThan("", duration)
 }
 
 func (s *InMemoryStore) GetUnreadNotificationsOlderThanForUser(userID string, duration string) ([]models.Notification, error) {
-	return []models.Notification{}, nil
+	return s.unreadNotificationsOlderThan(userID, duration)
+}
+
+func (s *InMemoryStore) unreadNotificationsOlderThan(userID string, duration string) ([]models.Notification, error) {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := s.Now().Add(-d)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]models.Notification, 0)
+	for _, n := range s.notifications {
+		if userID != "" && n.UserID != userID {
+			continue
+		}
+		if !n.Read && !n.Emailed && n.CreatedAt.Before(cutoff) {
+			res = append(res, n)
+		}
+	}
+	return res, nil
 }
 
 func (s *InMemoryStore) MarkNotificationAsEmailed(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n, ok := s.notifications[id]; ok {
+		n.Emailed = true
+		s.notifications[id] = n
+	}
 	return nil
 }
 
 func (s *InMemoryStore) GetTasksDueIn(duration string) ([]models.Task, error) {
-	return []models.Task{}, nil
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+	now := s.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]models.Task, 0)
+	for _, t := range s.tasks {
+		if !t.Completed && t.DueAt.After(now) && !t.DueAt.After(now.Add(d)) {
+			res = append(res, t)
+		}
+	}
+	return res, nil
 }
 
 func (s *InMemoryStore) GetEventsStartingIn(duration string) ([]models.Event, error) {
-	return []models.Event{}, nil
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+	now := s.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]models.Event, 0)
+	for _, e := range s.events {
+		if e.StartsAt.After(now) && !e.StartsAt.After(now.Add(d)) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+// Jobs
+
+// EnqueueJob stores a new pending job. If a job with the same key is already
+// stored, it is returned instead and the bool result is false.
+func (s *InMemoryStore) EnqueueJob(j models.Job) (models.Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j.ID == "" {
+		j.ID = uuid.New().String()
+	}
+	if j.Key == "" {
+		j.Key = j.ID
+	}
+	for _, existing := range s.jobs {
+		if existing.Key == j.Key {
+			return existing, false, nil
+		}
+	}
+	now := s.Now()
+	if j.RunAt.IsZero() {
+		j.RunAt = now
+	}
+	j.Status = models.JobPending
+	j.CreatedAt, j.UpdatedAt = now, now
+	s.jobs[j.ID] = j
+	return j, true, nil
+}
+
+// ClaimJobs marks up to limit jobs that are due at now as running for the
+// length of lease, counting an attempt for each. Running jobs whose lease
+// has expired are claimed again.
+func (s *InMemoryStore) ClaimJobs(now time.Time, lease time.Duration, limit int) ([]models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := make([]models.Job, 0)
+	for _, j := range s.jobs {
+		if (j.Status == models.JobPending && !j.RunAt.After(now)) ||
+			(j.Status == models.JobRunning && !j.LockedUntil.After(now)) {
+			due = append(due, j)
+		}
+	}
+	sort.Slice(due, func(a, b int) bool { return due[a].RunAt.Before(due[b].RunAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		due[i].Status = models.JobRunning
+		due[i].LockedUntil = now.Add(lease)
+		due[i].Attempts++
+		due[i].UpdatedAt = now
+		s.jobs[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+// CompleteJob removes a job that has succeeded. j is the job as claimed by
+// ClaimJobs; if its claim has since been lost, ErrJobLost is returned and
+// the job is left to whoever holds it now.
+func (s *InMemoryStore) CompleteJob(j models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ownsJob(j) {
+		return ErrJobLost
+	}
+	delete(s.jobs, j.ID)
+	return nil
+}
+
+// RescheduleJob returns a failed job, as claimed by ClaimJobs, to pending,
+// to be run again at runAt.
+func (s *InMemoryStore) RescheduleJob(j models.Job, runAt time.Time, lastErr string) error {
+	return s.updateJob(j, func(j *models.Job) {
+		j.Status = models.JobPending
+		j.RunAt = runAt
+		j.LastError = lastErr
+	})
+}
+
+// KillJob moves a failed job, as claimed by ClaimJobs, to the dead-letter
+// state, where it stays until it is retried with RetryJob.
+func (s *InMemoryStore) KillJob(j models.Job, lastErr string) error {
+	return s.updateJob(j, func(j *models.Job) {
+		j.Status = models.JobDead
+		j.LastError = lastErr
+	})
+}
+
+// ListJobs returns a page of up to limit jobs with the given status, most
+// recently updated first.
+func (s *InMemoryStore) ListJobs(status string, limit int) ([]models.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]models.Job, 0)
+	for _, j := range s.jobs {
+		if j.Status == status {
+			res = append(res, j)
+		}
+	}
+	sort.Slice(res, func(a, b int) bool { return res[a].UpdatedAt.After(res[b].UpdatedAt) })
+	if limit = pageSize(limit); len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// RetryJob returns a dead job to pending with its attempts reset, to run
+// as soon as possible. It returns ErrNotFound if no dead job has the ID.
+func (s *InMemoryStore) RetryJob(id string) (models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok || j.Status != models.JobDead {
+		return models.Job{}, ErrNotFound
+	}
+	j.Status = models.JobPending
+	j.Attempts = 0
+	j.RunAt = s.Now()
+	j.UpdatedAt = j.RunAt
+	s.jobs[id] = j
+	return j, nil
+}
+
+// ownsJob reports whether the claim on j is still held: the job is running
+// with the lease it was claimed with, so it has not been claimed again.
+func (s *InMemoryStore) ownsJob(j models.Job) bool {
+	stored, ok := s.jobs[j.ID]
+	return ok && stored.Status == models.JobRunning && stored.LockedUntil.Equal(j.LockedUntil)
+}
+
+func (s *InMemoryStore) updateJob(claimed models.Job, update func(*models.Job)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ownsJob(claimed) {
+		return ErrJobLost
+	}
+	j := s.jobs[claimed.ID]
+	update(&j)
+	j.UpdatedAt = s.Now()
+	s.jobs[j.ID] = j
+	return nil
+}
+
+// Leases
+
+// AcquireLease takes or renews the lease called name for holder until ttl
+// from now. It reports false if another holder has the lease.
+func (s *InMemoryStore) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	if l, ok := s.leases[name]; ok && l.holder != holder && l.expiresAt.After(now) {
+		return false, nil
+	}
+	s.leases[name] = lease{holder: holder, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// ReleaseLease gives up the lease called name if holder has it.
+func (s *InMemoryStore) ReleaseLease(name, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.leases[name]; ok && l.holder == holder {
+		delete(s.leases, name)
+	}
+	return nil
+}
+
+// Push subscriptions
+
+// SavePushSubscription stores sub, replacing the subscription with the same
+// endpoint, which keeps its ID, e.g. when a browser subscribes again or
+// another user signs in on the device.
+func (s *InMemoryStore) SavePushSubscription(sub models.PushSubscription) (models.PushSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub.ID = uuid.New().String()
+	sub.CreatedAt = s.Now()
+	for id, existing := range s.pushSubs {
+		if existing.Endpoint == sub.Endpoint {
+			sub.ID = id
+		}
+	}
+	s.pushSubs[sub.ID] = sub
+	return sub, nil
+}
+
+func (s *InMemoryStore) GetPushSubscription(id string) (models.PushSubscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sub, ok := s.pushSubs[id]
+	if !ok {
+		return models.PushSubscription{}, ErrNotFound
+	}
+	return sub, nil
+}
+
+// ListPushSubscriptions returns userID's subscriptions, oldest first.
+func (s *InMemoryStore) ListPushSubscriptions(userID string) ([]models.PushSubscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := []models.PushSubscription{}
+	for _, sub := range s.pushSubs {
+		if sub.UserID == userID {
+			res = append(res, sub)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.Before(res[j].CreatedAt) })
+	return res, nil
+}
+
+func (s *InMemoryStore) DeletePushSubscription(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pushSubs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.pushSubs, id)
+	return nil
+}
+
+// Device tokens
+
+// SaveDeviceToken stores d, replacing the registration with the same token,
+// which keeps its ID, e.g. when the app starts again or another user signs
+// in on the device.
+func (s *InMemoryStore) SaveDeviceToken(d models.DeviceToken) (models.DeviceToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d.ID = uuid.New().String()
+	d.CreatedAt = s.Now()
+	for id, existing := range s.deviceTokens {
+		if existing.Token == d.Token {
+			d.ID = id
+		}
+	}
+	s.deviceTokens[d.ID] = d
+	return d, nil
+}
+
+func (s *InMemoryStore) GetDeviceToken(id string) (models.DeviceToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.deviceTokens[id]
+	if !ok {
+		return models.DeviceToken{}, ErrNotFound
+	}
+	return d, nil
+}
+
+// ListDeviceTokens returns userID's devices, oldest first.
+func (s *InMemoryStore) ListDeviceTokens(userID string) ([]models.DeviceToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := []models.DeviceToken{}
+	for _, d := range s.deviceTokens {
+		if d.UserID == userID {
+			res = append(res, d)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.Before(res[j].CreatedAt) })
+	return res, nil
+}
+
+func (s *InMemoryStore) DeleteDeviceToken(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.deviceTokens[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.deviceTokens, id)
+	return nil
+}
+
+// Email suppression list
+
+// AddSuppression records s, replacing any suppression of the same address
+// and category.
+func (s *InMemoryStore) AddSuppression(sup models.Suppression) (models.Suppression, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sup.Email = normalizeEmail(sup.Email)
+	sup.CreatedAt = s.Now()
+	s.suppressions[suppressionKey{sup.Email, sup.Category}] = sup
+	return sup, nil
+}
+
+// RemoveSuppression lifts the suppression of email in category.
+func (s *InMemoryStore) RemoveSuppression(email, category string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := suppressionKey{normalizeEmail(email), category}
+	if _, ok := s.suppressions[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.suppressions, key)
+	return nil
+}
+
+// IsSuppressed reports whether email in category must not be sent to
+// email, because the address is suppressed in that category or in all.
+func (s *InMemoryStore) IsSuppressed(email, category string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	email = normalizeEmail(email)
+	_, inCategory := s.suppressions[suppressionKey{email, category}]
+	_, inAll := s.suppressions[suppressionKey{email, ""}]
+	return inCategory || inAll, nil
+}
+
+// ListSuppressions returns the suppressions of email, or of every address
+// if email is empty, newest first.
+func (s *InMemoryStore) ListSuppressions(email string) ([]models.Suppression, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	email = normalizeEmail(email)
+	res := []models.Suppression{}
+	for _, sup := range s.suppressions {
+		if email == "" || sup.Email == email {
+			res = append(res, sup)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.After(res[j].CreatedAt) })
+	return res, nil
+}
+
+// Webhooks
+
+// maxWebhookDeliveries is how many deliveries are logged for each webhook;
+// older ones are dropped.
+const maxWebhookDeliveries = 100
+
+func (s *InMemoryStore) CreateWebhook(h models.Webhook) (models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h.ID = uuid.New().String()
+	h.CreatedAt = s.Now()
+	s.webhooks[h.ID] = h
+	return h, nil
+}
+
+func (s *InMemoryStore) GetWebhook(id string) (models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h, ok := s.webhooks[id]
+	if !ok {
+		return models.Webhook{}, ErrNotFound
+	}
+	return h, nil
+}
+
+// ListWebhooks returns userID's webhooks, oldest first.
+func (s *InMemoryStore) ListWebhooks(userID string) ([]models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := []models.Webhook{}
+	for _, h := range s.webhooks {
+		if h.UserID == userID {
+			res = append(res, h)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.Before(res[j].CreatedAt) })
+	return res, nil
+}
+
+func (s *InMemoryStore) UpdateWebhook(id string, h models.Webhook) (models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.webhooks[id]; !ok {
+		return models.Webhook{}, ErrNotFound
+	}
+	h.ID = id
+	s.webhooks[id] = h
+	return h, nil
+}
+
+// DeleteWebhook deletes the webhook with ID id and its delivery log.
+func (s *InMemoryStore) DeleteWebhook(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.webhooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.webhooks, id)
+	delete(s.deliveries, id)
+	return nil
+}
+
+func (s *InMemoryStore) AddWebhookDelivery(d models.WebhookDelivery) (models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d.ID = uuid.New().String()
+	d.CreatedAt = s.Now()
+	entries := append(s.deliveries[d.WebhookID], d)
+	if len(entries) > maxWebhookDeliveries {
+		entries = entries[len(entries)-maxWebhookDeliveries:]
+	}
+	s.deliveries[d.WebhookID] = entries
+	return d, nil
+}
+
+// ListWebhookDeliveries returns up to limit of webhookID's deliveries, or
+// all of them if limit is 0, newest first.
+func (s *InMemoryStore) ListWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := s.deliveries[webhookID]
+	res := make([]models.WebhookDelivery, 0, len(entries))
+	for i := len(entries) - 1; i >= 0 && (limit == 0 || len(res) < limit); i-- {
+		res = append(res, entries[i])
+	}
+	return res, nil
+}
+
+// Close is a no-op; the in-memory store holds no resources.
+func (s *InMemoryStore) Close() error {
+	return nil
 }
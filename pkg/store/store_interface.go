@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
 )
@@ -10,6 +11,7 @@ import (
 type Store interface {
 	// Tasks
 	GetTasks(userID string) []models.Task
+	ListTasks(userID string, q TaskQuery) (TaskPage, error)
 	GetTask(id string) (models.Task, error)
 	CreateTask(t models.Task) models.Task
 	UpdateTask(id string, t models.Task) (models.Task, error)
@@ -18,11 +20,17 @@ type Store interface {
 	// Courses
 	GetCourses(userID string) []models.Course
 	GetCourse(id string) (models.Course, error)
+	GetCoursesByIDs(ids []string) ([]models.Course, error)
+	CountTasksByCourse(courseIDs []string) (map[string]TaskCounts, error)
 	CreateCourse(c models.Course) models.Course
 
 	// Events
 	GetEvents(userID string) []models.Event
+	ListEvents(userID string, f EventFilter) ([]models.Event, error)
+	GetEvent(id string) (models.Event, error)
 	CreateEvent(e models.Event) models.Event
+	UpdateEvent(id string, e models.Event) (models.Event, error)
+	DeleteEvent(id string) error
 
 	// Users
 	GetUser(id string) (models.User, error)
@@ -31,20 +39,74 @@ type Store interface {
 	CreateUser(u models.User) models.User
 	UpdateUser(id string, u models.User) (models.User, error)
 	UpdateUserPassword(id string, hashedPassword string) (models.User, error)
+	UpdateUserPreferences(id string, p models.Preferences) (models.User, error)
 	MarkUserVerified(id string) error
+	GetUsersWithDigest() ([]models.User, error)
+	MarkDigestSent(id string, at time.Time) error
 
 	// Notifications
-	GetNotifications(userID string) []models.Notification
+	GetNotifications(userID string, unreadOnly bool) []models.Notification
+	ListNotifications(userID string, q NotificationQuery) (NotificationPage, error)
+	GetNotification(id string) (models.Notification, error)
 	GetNotificationByReferenceID(refID string, nType string) (models.Notification, error)
+	GetNotificationByDedupKey(key string) (models.Notification, error)
 	CreateNotification(n models.Notification) models.Notification
+	CreateNotificationOnce(n models.Notification) (models.Notification, bool, error)
 	MarkNotificationAsRead(id string) error
+	SnoozeNotification(id string, until time.Time) (models.Notification, error)
+	GetNotificationsSnoozedUntil(t time.Time) ([]models.Notification, error)
+	ClearNotificationSnooze(id string) error
 	GetUnreadNotificationsOlderThan(duration string) ([]models.Notification, error)
 	GetUnreadNotificationsOlderThanForUser(userID string, duration string) ([]models.Notification, error)
 	MarkNotificationAsEmailed(id string) error
 
+	// Jobs
+	EnqueueJob(j models.Job) (models.Job, bool, error)
+	ClaimJobs(now time.Time, lease time.Duration, limit int) ([]models.Job, error)
+	CompleteJob(j models.Job) error
+	RescheduleJob(j models.Job, runAt time.Time, lastErr string) error
+	KillJob(j models.Job, lastErr string) error
+	ListJobs(status string, limit int) ([]models.Job, error)
+	RetryJob(id string) (models.Job, error)
+
+	// Leases
+	AcquireLease(name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(name, holder string) error
+
+	// Push subscriptions
+	SavePushSubscription(sub models.PushSubscription) (models.PushSubscription, error)
+	GetPushSubscription(id string) (models.PushSubscription, error)
+	ListPushSubscriptions(userID string) ([]models.PushSubscription, error)
+	DeletePushSubscription(id string) error
+
+	// Device tokens
+	SaveDeviceToken(d models.DeviceToken) (models.DeviceToken, error)
+	GetDeviceToken(id string) (models.DeviceToken, error)
+	ListDeviceTokens(userID string) ([]models.DeviceToken, error)
+	DeleteDeviceToken(id string) error
+
+	// Webhooks
+	CreateWebhook(h models.Webhook) (models.Webhook, error)
+	GetWebhook(id string) (models.Webhook, error)
+	ListWebhooks(userID string) ([]models.Webhook, error)
+	UpdateWebhook(id string, h models.Webhook) (models.Webhook, error)
+	DeleteWebhook(id string) error
+	AddWebhookDelivery(d models.WebhookDelivery) (models.WebhookDelivery, error)
+	ListWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error)
+
+	// Email suppression list
+	AddSuppression(s models.Suppression) (models.Suppression, error)
+	RemoveSuppression(email, category string) error
+	IsSuppressed(email, category string) (bool, error)
+	ListSuppressions(email string) ([]models.Suppression, error)
+
 	// Worker Helpers
 	GetTasksDueIn(duration string) ([]models.Task, error)
 	GetEventsStartingIn(duration string) ([]models.Event, error)
+
+	// Close releases the store's resources. The store must not be used
+	// afterwards.
+	Close() error
 }
 
 // NewStore returns a Store implementation. If MONGO_URI is provided, a MongoStore will be used.
@@ -0,0 +1,246 @@
+// Package webhook delivers users' changes to the endpoints they register as
+// webhooks. Each change is posted as a JSON Event, signed with the webhook's
+// secret, by a job the worker retries with backoff until the endpoint
+// accepts it.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/i18n"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/netguard"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+	"github.com/google/uuid"
+)
+
+const (
+	// JobDeliver is the kind of the jobs delivering an event to a webhook.
+	JobDeliver = "webhook.deliver"
+	// MaxAttempts is how many times an event is sent before giving up,
+	// about an hour after the first attempt with the queue's backoff.
+	MaxAttempts = 8
+
+	// SignatureHeader carries the delivery's signature, as made by Sign.
+	SignatureHeader = "X-StudyBuddy-Signature"
+	// EventHeader carries the event's type and DeliveryHeader its ID, which
+	// is the same for every attempt, so endpoints can ignore repeats.
+	EventHeader    = "X-StudyBuddy-Event"
+	DeliveryHeader = "X-StudyBuddy-Delivery"
+
+	// DefaultTolerance is how old a signature Verify accepts by default.
+	DefaultTolerance = 5 * time.Minute
+)
+
+// Event is the JSON body posted to a webhook.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Data is the task, event or notification that changed.
+	Data any `json:"data"`
+}
+
+// NewSecret returns a new random secret to sign a webhook's deliveries with.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header for body sent at t: "t=<unix
+// time>,v1=<signature>", where the signature is the hex HMAC-SHA256 of the
+// time, a dot and the body, keyed by secret. Signing the time keeps
+// deliveries from being replayed later.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+func signature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ErrInvalidSignature is returned by Verify for a delivery that was not
+// signed with the secret, or was signed too long ago.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Verify checks that header, a delivery's SignatureHeader, signs body with
+// secret less than tolerance before now. It is how endpoints check that a
+// delivery came from StudyBuddy.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+		return ErrInvalidSignature
+	}
+	want := signature(secret, ts, body)
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(want)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// Sender posts events to webhooks.
+type Sender struct {
+	// Client sends the deliveries; nil uses one with a 10 second timeout
+	// that only connects to public addresses.
+	Client *http.Client
+	// Now returns the current time, used for signatures; nil means
+	// time.Now.
+	Now func() time.Time
+}
+
+// defaultClient only connects to public addresses, so webhooks cannot
+// reach the server's own network, and does not follow redirects, which
+// could send the signed event somewhere the user did not register.
+var defaultClient = netguard.NewClient(10 * time.Second)
+
+// Deliver posts body, the event eventID of type event, to h and returns
+// the log of the attempt, which succeeded if the endpoint responded with a
+// 2xx status. It gives up once ctx is done.
+func (s *Sender) Deliver(ctx context.Context, h models.Webhook, eventID, event string, body []byte, attempt int) (d models.WebhookDelivery) {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	client := s.Client
+	if client == nil {
+		client = defaultClient
+	}
+	d = models.WebhookDelivery{WebhookID: h.ID, EventID: eventID, Event: event, Attempt: attempt}
+	// The duration is measured by the monotonic clock, which a fake Now
+	// would stop
+	started := time.Now()
+	defer func() { d.Duration = time.Since(started) }()
+	start := now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "StudyBuddy-Webhooks/1.0")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, eventID)
+	req.Header.Set(SignatureHeader, Sign(h.Secret, start, body))
+	resp, err := client.Do(req)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	d.StatusCode = resp.StatusCode
+	if !d.Succeeded() {
+		d.Error = fmt.Sprintf("endpoint responded %s", resp.Status)
+	}
+	return d
+}
+
+// Marshal returns the body of the event of type event with data.
+func Marshal(id, event string, data any, now time.Time) ([]byte, error) {
+	return json.Marshal(Event{ID: id, Type: event, CreatedAt: now.UTC(), Data: data})
+}
+
+// Dispatcher enqueues deliveries of users' changes to the webhooks
+// subscribed to them.
+type Dispatcher struct {
+	Store store.Store
+	// Now returns the current time; nil means time.Now.
+	Now func() time.Time
+}
+
+// Dispatch enqueues a delivery of the change data, of type event, to each
+// of userID's webhooks subscribed to it. Errors are logged, as the change
+// has already been made.
+func (d *Dispatcher) Dispatch(userID, event string, data any) {
+	if err := d.dispatch(userID, event, data); err != nil {
+		log.Printf("Error dispatching %s webhooks for user %s: %v", event, userID, err)
+	}
+}
+
+func (d *Dispatcher) dispatch(userID, event string, data any) error {
+	hooks, err := d.Store.ListWebhooks(userID)
+	if err != nil {
+		return err
+	}
+	var subscribed []models.Webhook
+	for _, h := range hooks {
+		if h.Subscribed(event) {
+			subscribed = append(subscribed, h)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	if d.Now != nil {
+		now = d.Now()
+	}
+	// Notifications are sent with their text in the user's language
+	if n, ok := data.(models.Notification); ok {
+		u, _ := d.Store.GetUser(userID)
+		n.Message = i18n.For(u).Notification(n)
+		data = n
+	}
+	id := uuid.New().String()
+	body, err := Marshal(id, event, data, now)
+	if err != nil {
+		return err
+	}
+	for _, h := range subscribed {
+		_, _, err := d.Store.EnqueueJob(models.Job{
+			Kind: JobDeliver,
+			Key:  fmt.Sprintf("webhook:%s:%s", id, h.ID),
+			Payload: map[string]string{
+				"webhookId": h.ID,
+				"eventId":   id,
+				"event":     event,
+				"body":      string(body),
+			},
+			MaxAttempts: MaxAttempts,
+			RunAt:       now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
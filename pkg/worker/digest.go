@@ -0,0 +1,154 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/email"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/i18n"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/jobs"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+)
+
+const (
+	// digestGrace is how late a digest may still be sent, e.g. after the
+	// worker was down at its scheduled time. Later than that it is skipped
+	// until the next one is due.
+	digestGrace = 2 * time.Hour
+	// maxDigestItems caps each section of a digest.
+	maxDigestItems = 20
+)
+
+// CheckDigests enqueues a digest email for each user whose digest has come
+// due and not been sent yet. It stops early, returning ctx's error, once
+// ctx is done.
+func (w *Worker) CheckDigests(ctx context.Context) error {
+	users, err := w.Store.GetUsersWithDigest()
+	if err != nil {
+		return fmt.Errorf("getting users with digests: %w", err)
+	}
+
+	now := w.Now()
+	for _, u := range users {
+		// Out of time: the rest is picked up by the next scan
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		prefs := u.Prefs()
+		due := prefs.Digest.LastDue(now, u.Location(), prefs.WeekStart)
+		if now.Sub(due) > digestGrace || (u.DigestSentAt != nil && !u.DigestSentAt.Before(due)) {
+			continue
+		}
+		err := w.Queue.Enqueue(JobSendDigest, fmt.Sprintf("digest:%s:%d", u.ID, due.Unix()),
+			map[string]string{"userId": u.ID, "due": strconv.FormatInt(due.Unix(), 10)}, now)
+		if err != nil {
+			return fmt.Errorf("enqueueing digest for user %s: %w", u.ID, err)
+		}
+	}
+	return nil
+}
+
+func (w *Worker) sendDigest(ctx context.Context, job models.Job) error {
+	unix, err := strconv.ParseInt(job.Payload["due"], 10, 64)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	due := time.Unix(unix, 0)
+	user, err := w.Store.GetUser(job.Payload["userId"])
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.DigestSentAt != nil && !user.DigestSentAt.Before(due) {
+		return nil
+	}
+
+	prefs := user.Prefs()
+	if user.IsVerified && prefs.HasChannel(models.ChannelEmail) && prefs.Digest != nil {
+		data, err := w.buildDigest(user, *prefs.Digest, w.Now())
+		if err != nil {
+			return err
+		}
+		// Nothing to report is not worth an email
+		if len(data.Sections) > 0 {
+			if err := email.SendDigestEmail(ctx, w.Mailer, user.Email, i18n.For(user).Locale, data); err != nil {
+				return fmt.Errorf("sending digest to %s: %w", user.Email, err)
+			}
+			log.Printf("Sent digest to user %s", user.ID)
+		}
+	}
+	return w.Store.MarkDigestSent(user.ID, due)
+}
+
+// buildDigest gathers u's overdue and upcoming tasks, upcoming events and
+// unread notifications for a digest sent at now, in u's locale. Upcoming
+// items are those in the next day, or week for a weekly digest. Empty
+// sections are left out.
+func (w *Worker) buildDigest(u models.User, d models.Digest, now time.Time) (email.DigestData, error) {
+	l := i18n.For(u)
+	title, period := l.T("digest.daily.title", nil), 24*time.Hour
+	if d.Frequency == models.DigestWeekly {
+		title, period = l.T("digest.weekly.title", nil), 7*24*time.Hour
+	}
+	until := now.Add(period)
+
+	var overdue, upcoming []models.Task
+	for _, t := range w.Store.GetTasks(u.ID) {
+		switch {
+		case t.Completed:
+		case t.DueAt.Before(now):
+			overdue = append(overdue, t)
+		case t.DueAt.Before(until):
+			upcoming = append(upcoming, t)
+		}
+	}
+	events, err := w.Store.ListEvents(u.ID, store.EventFilter{From: now, To: until})
+	if err != nil {
+		return email.DigestData{}, err
+	}
+	notifications := w.Store.GetNotifications(u.ID, true)
+
+	data := email.DigestData{Title: title, Intro: l.Format(now, "date")}
+	section := func(heading string, n int, item func(i int) string) {
+		if n == 0 {
+			return
+		}
+		s := email.DigestSection{Heading: heading}
+		for i := 0; i < n && i < maxDigestItems; i++ {
+			s.Items = append(s.Items, item(i))
+		}
+		if n > maxDigestItems {
+			s.Items = append(s.Items, l.T("digest.more", map[string]string{"count": strconv.Itoa(n - maxDigestItems)}))
+		}
+		data.Sections = append(data.Sections, s)
+	}
+	sortTasks := func(ts []models.Task) {
+		sort.Slice(ts, func(i, j int) bool { return ts[i].DueAt.Before(ts[j].DueAt) })
+	}
+	sortTasks(overdue)
+	sortTasks(upcoming)
+	item := func(key, title, param string, t time.Time) string {
+		return l.T(key, map[string]string{"title": title, param: i18n.Time(t)})
+	}
+	section(l.T("digest.overdue", nil), len(overdue), func(i int) string {
+		return item("digest.item.overdue", overdue[i].Title, "due", overdue[i].DueAt)
+	})
+	section(l.T("digest.due_soon", nil), len(upcoming), func(i int) string {
+		return item("digest.item.due", upcoming[i].Title, "due", upcoming[i].DueAt)
+	})
+	section(l.T("digest.events", nil), len(events), func(i int) string {
+		return item("digest.item.event", events[i].Title, "start", events[i].StartsAt)
+	})
+	section(l.T("digest.unread", nil), len(notifications), func(i int) string {
+		return l.Notification(notifications[i])
+	})
+	return data, nil
+}
@@ -0,0 +1,193 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/email"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/i18n"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/jobs"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/push"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+)
+
+// PushPayload is the JSON message pushed to a device for a notification.
+type PushPayload struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	ReferenceID string `json:"referenceId"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+}
+
+// enqueuePush enqueues a push of n, which the worker has just created, to
+// each of u's browsers' push subscriptions and to each of u's mobile devices.
+// Pushes are held back until u's quiet hours end.
+func (w *Worker) enqueuePush(n models.Notification, u models.User) error {
+	prefs := u.Prefs()
+	if (w.WebPush == nil && w.Push == nil) || !prefs.HasChannel(models.ChannelPush) {
+		return nil
+	}
+	runAt := afterQuietHours(prefs, w.Now(), u.Location())
+	if w.WebPush != nil {
+		subs, err := w.Store.ListPushSubscriptions(u.ID)
+		if err != nil {
+			return err
+		}
+		for _, sub := range subs {
+			err := w.Queue.Enqueue(JobSendPush, fmt.Sprintf("push:%s:%s", n.ID, sub.ID),
+				map[string]string{"notificationId": n.ID, "subscriptionId": sub.ID}, runAt)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if w.Push != nil {
+		devices, err := w.Store.ListDeviceTokens(u.ID)
+		if err != nil {
+			return err
+		}
+		for _, d := range devices {
+			err := w.Queue.Enqueue(JobSendDevicePush, fmt.Sprintf("device:%s:%s", n.ID, d.ID),
+				map[string]string{"notificationId": n.ID, "deviceId": d.ID}, runAt)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterQuietHours returns now, or the end of the quiet hours now is in.
+func afterQuietHours(p models.Preferences, now time.Time, loc *time.Location) time.Time {
+	if !p.InQuietHours(now, loc) {
+		return now
+	}
+	local := now.In(loc)
+	end := time.Date(local.Year(), local.Month(), local.Day(), p.QuietHours.End/60, p.QuietHours.End%60, 0, 0, loc)
+	if !end.After(now) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// notificationToPush returns the notification with ID id and its text in
+// its user's language, or ok false if it is no longer to be pushed because
+// it has been deleted or read since the push was enqueued.
+func (w *Worker) notificationToPush(id string) (n models.Notification, text string, ok bool, err error) {
+	n, err = w.Store.GetNotification(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return n, "", false, nil
+	}
+	if err != nil {
+		return n, "", false, err
+	}
+	if n.Read {
+		return n, "", false, nil
+	}
+	user, err := w.Store.GetUser(n.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		return n, "", false, jobs.Permanent(err)
+	}
+	if err != nil {
+		return n, "", false, err
+	}
+	return n, i18n.For(user).Notification(n), true, nil
+}
+
+// pushResult returns the job result for the error err from pushing to a
+// device, deleting the device with remove if it is gone. Each device is
+// pushed to by its own job, so a failing device does not hold up the rest.
+func pushResult(err error, device string, remove func() error) error {
+	var status *push.StatusError
+	switch {
+	case errors.Is(err, push.ErrGone):
+		log.Printf("Deleting expired %s", device)
+		if err := remove(); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		return nil
+	case errors.As(err, &status) && !status.Temporary():
+		return jobs.Permanent(fmt.Errorf("pushing to %s: %w", device, err))
+	case err != nil:
+		return fmt.Errorf("pushing to %s: %w", device, err)
+	}
+	log.Printf("Pushed to %s", device)
+	return nil
+}
+
+// sendPush pushes a notification to one push subscription, unless the
+// notification has been read since the job was enqueued. A subscription the
+// push service no longer knows is deleted.
+func (w *Worker) sendPush(ctx context.Context, job models.Job) error {
+	if w.WebPush == nil {
+		return jobs.Permanent(errors.New("web push is not configured"))
+	}
+	n, text, ok, err := w.notificationToPush(job.Payload["notificationId"])
+	if !ok {
+		return err
+	}
+	sub, err := w.Store.GetPushSubscription(job.Payload["subscriptionId"])
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// The device may have been handed to another user since
+	if sub.UserID != n.UserID {
+		return nil
+	}
+
+	payload, err := json.Marshal(PushPayload{
+		ID:          n.ID,
+		Type:        n.Type,
+		ReferenceID: n.ReferenceID,
+		Title:       email.Brand,
+		Body:        text,
+	})
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	return pushResult(w.WebPush.Send(ctx, sub, payload), "push subscription "+sub.ID, func() error {
+		return w.Store.DeletePushSubscription(sub.ID)
+	})
+}
+
+// sendDevicePush pushes a notification to one mobile device, unless the
+// notification has been read since the job was enqueued. A device whose
+// token the provider no longer accepts is deleted.
+func (w *Worker) sendDevicePush(ctx context.Context, job models.Job) error {
+	if w.Push == nil {
+		return jobs.Permanent(errors.New("mobile push is not configured"))
+	}
+	n, text, ok, err := w.notificationToPush(job.Payload["notificationId"])
+	if !ok {
+		return err
+	}
+	d, err := w.Store.GetDeviceToken(job.Payload["deviceId"])
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Another user may have signed in on the device since
+	if d.UserID != n.UserID {
+		return nil
+	}
+
+	err = w.Push.Send(ctx, d.Token, push.Message{
+		Title: email.Brand,
+		Body:  text,
+		Data:  map[string]string{"id": n.ID, "type": n.Type, "referenceId": n.ReferenceID},
+	})
+	return pushResult(err, "device "+d.ID, func() error {
+		return w.Store.DeleteDeviceToken(d.ID)
+	})
+}
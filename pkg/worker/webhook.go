@@ -0,0 +1,36 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+)
+
+// deliverWebhook posts an event to a webhook and logs the attempt. A failed
+// attempt fails the job, so it is retried with backoff, unless the webhook
+// has been deleted or turned off since the event.
+func (w *Worker) deliverWebhook(ctx context.Context, job models.Job) error {
+	h, err := w.Store.GetWebhook(job.Payload["webhookId"])
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !h.Active {
+		return nil
+	}
+	d := w.Webhooks.Deliver(ctx, h, job.Payload["eventId"], job.Payload["event"], []byte(job.Payload["body"]), job.Attempts)
+	if _, err := w.Store.AddWebhookDelivery(d); err != nil {
+		log.Printf("Error logging delivery to webhook %s: %v", h.ID, err)
+	}
+	if !d.Succeeded() {
+		return fmt.Errorf("delivering %s to webhook %s: %s", d.Event, h.ID, d.Error)
+	}
+	log.Printf("Delivered %s to webhook %s", d.Event, h.ID)
+	return nil
+}
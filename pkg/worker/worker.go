@@ -1,124 +1,521 @@
 package worker
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/email"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/i18n"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/jobs"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/models"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/push"
 	"github.com/RandithaK/StudyBuddy_Backend/pkg/store"
+	"github.com/RandithaK/StudyBuddy_Backend/pkg/webhook"
+	"github.com/google/uuid"
+)
+
+// Job kinds run by the worker. The scans run every tick; a send job is
+// enqueued for each notification that is due to be emailed, for each push
+// subscription and mobile device a new notification is pushed to, for each
+// digest that has come due and for each change delivered to a webhook.
+const (
+	JobScanTasks      = "scan.tasks"
+	JobScanEvents     = "scan.events"
+	JobScanSnoozed    = "scan.snoozed"
+	JobScanUnread     = "scan.unread"
+	JobScanDigests    = "scan.digests"
+	JobSendEmail      = "email.send"
+	JobSendDigest     = "digest.send"
+	JobSendPush       = "push.send"
+	JobSendDevicePush = "push.device"
+	JobDeliverWebhook = webhook.JobDeliver
+)
+
+const (
+	jobsPerTick     = 100
+	scanMaxAttempts = 3
+	// tickBudget bounds a tick started by Start, so it ends before the next.
+	tickBudget = 50 * time.Second
+)
+
+var scanJobs = []string{JobScanTasks, JobScanEvents, JobScanSnoozed, JobScanUnread, JobScanDigests}
+
+// leaseName is the lease a worker must hold to run a cycle, so that when
+// several instances run a worker only one of them runs each cycle. It is
+// released when the cycle ends; leaseTTL, longer than a cycle, only matters
+// if the holder stops mid-cycle.
+const (
+	leaseName = "worker"
+	leaseTTL  = 90 * time.Second
 )
 
 type Worker struct {
 	Store store.Store
+	Queue *jobs.Queue
+	// ID identifies this worker as the holder of the worker lease.
+	ID string
+	// Now returns the current time, deciding which reminders are due. The
+	// queue uses it too.
+	Now func() time.Time
+	// Mailer sends the worker's emails. NewWorker sets a LogMailer, which
+	// only prints them.
+	Mailer email.Mailer
+	// WebPush, if set, pushes the notifications the worker creates to its
+	// users' browsers.
+	WebPush *push.WebPush
+	// Push, if set, pushes the notifications the worker creates to its
+	// users' mobile devices.
+	Push push.PushProvider
+	// Webhooks delivers changes to users' webhooks. NewWorker sets a
+	// Sender with the default client and the worker's clock.
+	Webhooks *webhook.Sender
+
+	// running stops a worker from running cycles concurrently, since its
+	// own lease does not exclude it.
+	running sync.Mutex
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// CycleSummary reports what a worker cycle did.
+type CycleSummary struct {
+	// Skipped is set when another cycle was running, here or on another
+	// instance, and this one did nothing.
+	Skipped bool
+	JobsRun int
+	// OutOfTime is set when the cycle stopped at its time budget. Jobs left
+	// due run in the next cycle.
+	OutOfTime bool
+	Duration  time.Duration
 }
 
 func NewWorker(s store.Store) *Worker {
-	return &Worker{Store: s}
+	w := &Worker{Store: s, Queue: jobs.New(s), ID: workerID(), Now: time.Now, Mailer: email.LogMailer{}}
+	w.Queue.Now = func() time.Time { return w.Now() }
+	w.Webhooks = &webhook.Sender{Now: func() time.Time { return w.Now() }}
+	w.Queue.Handle(JobScanTasks, func(ctx context.Context, _ models.Job) error { return w.CheckUpcomingTasks(ctx) })
+	w.Queue.Handle(JobScanEvents, func(ctx context.Context, _ models.Job) error { return w.CheckUpcomingEvents(ctx) })
+	w.Queue.Handle(JobScanSnoozed, func(ctx context.Context, _ models.Job) error { return w.CheckSnoozedNotifications(ctx) })
+	w.Queue.Handle(JobScanUnread, func(ctx context.Context, _ models.Job) error { return w.CheckUnreadNotifications(ctx) })
+	w.Queue.Handle(JobScanDigests, func(ctx context.Context, _ models.Job) error { return w.CheckDigests(ctx) })
+	w.Queue.Handle(JobSendEmail, w.sendEmail)
+	w.Queue.Handle(JobSendDigest, w.sendDigest)
+	w.Queue.Handle(JobSendPush, w.sendPush)
+	w.Queue.Handle(JobSendDevicePush, w.sendDevicePush)
+	w.Queue.Handle(JobDeliverWebhook, w.deliverWebhook)
+	return w
 }
 
+// Start ticks every minute in the background until Stop is called.
 func (w *Worker) Start() {
+	w.stop = make(chan struct{})
+	w.stopped = make(chan struct{})
 	ticker := time.NewTicker(1 * time.Minute) // Check every minute
 	go func() {
-		for range ticker.C {
-			w.CheckUpcomingTasks()
-			w.CheckUpcomingEvents()
-			w.CheckUnreadNotifications()
+		defer close(w.stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.Tick()
+			}
+		}
+	}()
+}
+
+// Stop stops a started worker and waits for the cycle in progress, if any,
+// to finish. It returns ctx's error if ctx is done first.
+func (w *Worker) Stop(ctx context.Context) error {
+	if w.stop == nil {
+		return nil
+	}
+	w.stopOnce.Do(func() { close(w.stop) })
+	select {
+	case <-w.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Tick runs a cycle within tickBudget.
+func (w *Worker) Tick() {
+	if _, err := w.RunCycle(tickBudget); err != nil {
+		log.Printf("Error running worker cycle: %v", err)
+	}
+}
+
+// RunCycle enqueues this minute's scans and runs up to jobsPerTick jobs that
+// are due, including email sends being retried, stopping once budget has
+// passed; a zero budget is unbounded. Jobs are run with a context ending
+// with the budget, so a slow email, push or webhook cannot carry the cycle
+// past it. Scans are keyed by minute, so a failing
+// scan is retried on its own rather than piling up, and each dead one is kept
+// for inspection.
+//
+// It is safe to call concurrently and from several instances: only one cycle
+// runs at a time and the others are skipped.
+func (w *Worker) RunCycle(budget time.Duration) (CycleSummary, error) {
+	start := w.Now()
+	var deadline time.Time
+	ctx := context.Background()
+	if budget > 0 {
+		deadline = start.Add(budget)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+	if !w.running.TryLock() {
+		return CycleSummary{Skipped: true}, nil
+	}
+	defer w.running.Unlock()
+
+	ok, err := w.Store.AcquireLease(leaseName, w.ID, leaseTTL)
+	if err != nil {
+		return CycleSummary{}, fmt.Errorf("acquiring worker lease: %w", err)
+	}
+	if !ok {
+		// Another instance is running this cycle
+		return CycleSummary{Skipped: true}, nil
+	}
+	defer func() {
+		if err := w.Store.ReleaseLease(leaseName, w.ID); err != nil {
+			log.Printf("Error releasing worker lease: %v", err)
 		}
 	}()
+
+	now := start
+	minute := now.Truncate(time.Minute).Unix()
+	for _, kind := range scanJobs {
+		_, _, err := w.Store.EnqueueJob(models.Job{
+			Kind:        kind,
+			Key:         fmt.Sprintf("%s:%d", kind, minute),
+			MaxAttempts: scanMaxAttempts,
+			RunAt:       now,
+		})
+		if err != nil {
+			log.Printf("Error enqueueing %s: %v", kind, err)
+		}
+	}
+	// Keep going while jobs are due, so emails enqueued by this cycle's scans
+	// are sent in the same cycle. Jobs are claimed one at a time so none is
+	// claimed after the budget has run out.
+	var summary CycleSummary
+	for summary.JobsRun < jobsPerTick {
+		if (!deadline.IsZero() && !w.Now().Before(deadline)) || ctx.Err() != nil {
+			summary.OutOfTime = true
+			break
+		}
+		n, err := w.Queue.RunDue(ctx, 1)
+		if err != nil {
+			log.Printf("Error claiming jobs: %v", err)
+			break
+		}
+		if n == 0 {
+			break
+		}
+		summary.JobsRun += n
+	}
+	summary.Duration = w.Now().Sub(start)
+	return summary, nil
 }
 
-func (w *Worker) CheckUpcomingTasks() {
-	// Get tasks due in the next 24 hours
-	tasks, err := w.Store.GetTasksDueIn("24h")
+// workerID returns a name for this worker that is unique across instances.
+func workerID() string {
+	host, err := os.Hostname()
 	if err != nil {
-		log.Printf("Error getting upcoming tasks: %v", err)
-		return
+		host = "worker"
 	}
+	return host + "-" + uuid.New().String()[:8]
+}
 
+// CheckUpcomingTasks creates a notification for each task reminder that
+// has come due. It stops early, returning ctx's error, once ctx is done.
+func (w *Worker) CheckUpcomingTasks(ctx context.Context) error {
+	// Get tasks due within the longest lead time anyone can choose, then
+	// keep those due within their owner's own lead time
+	tasks, err := w.Store.GetTasksDueIn(models.MaxReminderLead.String())
+	if err != nil {
+		return fmt.Errorf("getting upcoming tasks: %w", err)
+	}
+
+	now := w.Now()
+	users := w.userCache()
 	for _, t := range tasks {
-		// Check if we already created a notification for this task
-		_, err := w.Store.GetNotificationByReferenceID(t.ID, "TASK_DUE")
-		if err == nil {
-			// Notification already exists
+		// Out of time: the rest is picked up by the next scan
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		u := users(t.UserID)
+		prefs := u.Prefs()
+		if len(prefs.Channels) == 0 {
+			continue
+		}
+		fireAt, ok := dueReminder(t.RemindersFor(prefs), t.DueAt, now)
+		if !ok {
+			continue
+		}
+
+		// Check if we already created a notification for this reminder
+		key := reminderKey("TASK_DUE", t.ID, fireAt)
+		if _, err := w.Store.GetNotificationByDedupKey(key); err == nil {
 			continue
 		}
 
 		// Create notification
 		n := models.Notification{
 			UserID:      t.UserID,
-			Message:     fmt.Sprintf("Task '%s' is due in less than 24 hours!", t.Title),
 			Type:        "TASK_DUE",
+			Params:      map[string]string{"title": t.Title, "due": i18n.Time(t.DueAt)},
 			ReferenceID: t.ID,
 			Read:        false,
 			Emailed:     false,
+			DedupKey:    key,
+		}
+		// Another instance may have created it since the check above
+		if n, created, err := w.Store.CreateNotificationOnce(n); err != nil {
+			return fmt.Errorf("creating notification for task %s: %w", t.ID, err)
+		} else if created {
+			log.Printf("Created notification for task %s", t.ID)
+			if err := w.enqueuePush(n, u); err != nil {
+				log.Printf("Error enqueueing push for notification %s: %v", n.ID, err)
+			}
 		}
-		w.Store.CreateNotification(n)
-		log.Printf("Created notification for task %s", t.ID)
 	}
+	return nil
 }
 
-func (w *Worker) CheckUpcomingEvents() {
-	events, err := w.Store.GetEventsStartingIn("24h")
+// CheckUpcomingEvents creates a notification for each event reminder that
+// has come due. It stops early, returning ctx's error, once ctx is done.
+func (w *Worker) CheckUpcomingEvents(ctx context.Context) error {
+	events, err := w.Store.GetEventsStartingIn(models.MaxReminderLead.String())
 	if err != nil {
-		log.Printf("Error getting upcoming events: %v", err)
-		return
+		return fmt.Errorf("getting upcoming events: %w", err)
 	}
 
+	now := w.Now()
+	users := w.userCache()
 	for _, e := range events {
-		_, err := w.Store.GetNotificationByReferenceID(e.ID, "EVENT_START")
-		if err == nil {
+		// Out of time: the rest is picked up by the next scan
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		u := users(e.UserID)
+		prefs := u.Prefs()
+		if len(prefs.Channels) == 0 {
+			continue
+		}
+		fireAt, ok := dueReminder(e.RemindersFor(prefs), e.StartsAt, now)
+		if !ok {
+			continue
+		}
+
+		key := reminderKey("EVENT_START", e.ID, fireAt)
+		if _, err := w.Store.GetNotificationByDedupKey(key); err == nil {
 			continue
 		}
 
 		n := models.Notification{
 			UserID:      e.UserID,
-			Message:     fmt.Sprintf("Event '%s' is starting in less than 24 hours!", e.Title),
 			Type:        "EVENT_START",
+			Params:      map[string]string{"title": e.Title, "start": i18n.Time(e.StartsAt)},
 			ReferenceID: e.ID,
 			Read:        false,
 			Emailed:     false,
+			DedupKey:    key,
+		}
+		if n, created, err := w.Store.CreateNotificationOnce(n); err != nil {
+			return fmt.Errorf("creating notification for event %s: %w", e.ID, err)
+		} else if created {
+			log.Printf("Created notification for event %s", e.ID)
+			if err := w.enqueuePush(n, u); err != nil {
+				log.Printf("Error enqueueing push for notification %s: %v", n.ID, err)
+			}
 		}
-		w.Store.CreateNotification(n)
-		log.Printf("Created notification for event %s", e.ID)
 	}
+	return nil
 }
 
-func (w *Worker) CheckUnreadNotifications() {
-	// Get unread notifications older than 1 hour
-	notifications, err := w.Store.GetUnreadNotificationsOlderThan("1h")
+// dueReminder returns when the latest of reminders that is due by now fired,
+// for an item due or starting at t. Earlier reminders are covered by it, so
+// an item created shortly before it is due gets one reminder rather than one
+// for every reminder it has already passed.
+func dueReminder(reminders []models.Reminder, t, now time.Time) (time.Time, bool) {
+	var latest time.Time
+	for _, r := range reminders {
+		if at := r.FireAt(t); !at.After(now) && at.After(latest) {
+			latest = at
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// reminderKey identifies the reminder firing at fireAt for an item. Moving
+// the item or its reminders changes the key, so the moved reminder is sent.
+func reminderKey(nType, refID string, fireAt time.Time) string {
+	return fmt.Sprintf("%s:%s:%d", nType, refID, fireAt.Unix())
+}
+
+// CheckSnoozedNotifications sends snoozed notifications again as new,
+// unread notifications once their snooze has ended. It stops early,
+// returning ctx's error, once ctx is done.
+func (w *Worker) CheckSnoozedNotifications(ctx context.Context) error {
+	notifications, err := w.Store.GetNotificationsSnoozedUntil(w.Now())
 	if err != nil {
-		log.Printf("Error getting unread notifications: %v", err)
-		return
+		return fmt.Errorf("getting snoozed notifications: %w", err)
 	}
 
+	users := w.userCache()
 	for _, n := range notifications {
-		user, err := w.Store.GetUser(n.UserID)
+		// Out of time: the rest is picked up by the next scan
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key := fmt.Sprintf("snooze:%s:%d", n.ID, n.SnoozedUntil.Unix())
+		resent, created, err := w.Store.CreateNotificationOnce(models.Notification{
+			UserID:      n.UserID,
+			Message:     n.Message,
+			Type:        n.Type,
+			Params:      n.Params,
+			ReferenceID: n.ReferenceID,
+			DedupKey:    key,
+		})
 		if err != nil {
-			log.Printf("Error getting user %s: %v", n.UserID, err)
-			continue
+			return fmt.Errorf("resending snoozed notification %s: %w", n.ID, err)
 		}
+		if created {
+			log.Printf("Resent snoozed notification %s", n.ID)
+			if err := w.enqueuePush(resent, users(n.UserID)); err != nil {
+				log.Printf("Error enqueueing push for notification %s: %v", resent.ID, err)
+			}
+		}
+		if err := w.Store.ClearNotificationSnooze(n.ID); err != nil {
+			log.Printf("Error clearing snooze of notification %s: %v", n.ID, err)
+		}
+	}
+	return nil
+}
+
+// userCache returns a lookup of users that remembers each user for the rest
+// of a check, so a user with many items costs one lookup. Users that cannot
+// be loaded get the default time zone and preferences.
+func (w *Worker) userCache() func(userID string) models.User {
+	cache := map[string]models.User{}
+	return func(userID string) models.User {
+		if u, ok := cache[userID]; ok {
+			return u
+		}
+		u, err := w.Store.GetUser(userID)
+		if err != nil {
+			u = models.User{ID: userID}
+		}
+		cache[userID] = u
+		return u
+	}
+}
 
-		// Only send email if user is verified
-		if !user.IsVerified {
-			log.Printf("Skipping email for unverified user %s", user.Email)
-			// We still mark as emailed so we don't keep checking?
-			// Or we leave it as not emailed?
-			// If we leave it, we'll keep checking every minute.
-			// Better to mark it as emailed (or "processed") to avoid loop.
+// EmailFallback decides what to do about emailing unread notification n to
+// u at now. It reports send when the email is due now, and skip when n will
+// never be emailed and can be marked as emailed without sending anything.
+// When neither is set the email is not due yet, or is held back by the
+// user's quiet hours, and n should be looked at again later.
+func EmailFallback(u models.User, n models.Notification, now time.Time) (send, skip bool) {
+	prefs := u.Prefs()
+	// Only send email if user is verified and wants email, and not batched
+	// into a digest
+	if !u.IsVerified || !prefs.HasChannel(models.ChannelEmail) || prefs.Digest != nil {
+		return false, true
+	}
+	// Without in-app notifications there is nothing to wait to be read
+	delay := prefs.EmailDelay
+	if !prefs.HasChannel(models.ChannelInApp) {
+		delay = 0
+	}
+	if now.Sub(n.CreatedAt) < delay || prefs.InQuietHours(now, u.Location()) {
+		return false, false
+	}
+	return true, false
+}
+
+// CheckUnreadNotifications enqueues an email for each unread notification
+// that is due to be emailed. Notifications that will never be emailed are
+// marked as emailed so they are not looked at again. It stops early,
+// returning ctx's error, once ctx is done.
+func (w *Worker) CheckUnreadNotifications(ctx context.Context) error {
+	// Email delays are per user, so get every unread notification and let
+	// EmailFallback decide which are due
+	notifications, err := w.Store.GetUnreadNotificationsOlderThan("0s")
+	if err != nil {
+		return fmt.Errorf("getting unread notifications: %w", err)
+	}
+
+	now := w.Now()
+	users := w.userCache()
+	for _, n := range notifications {
+		// Out of time: the rest is picked up by the next scan
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		send, skip := EmailFallback(users(n.UserID), n, now)
+		if skip {
+			// Mark as emailed so we don't keep checking every minute
+			log.Printf("Skipping email for notification %s", n.ID)
 			w.Store.MarkNotificationAsEmailed(n.ID)
 			continue
 		}
+		if !send {
+			continue
+		}
 
-		err = email.SendNotificationEmail(user.Email, "You have an unread notification", n.Message)
+		// A notification already being sent or retried keeps its job
+		err := w.Queue.Enqueue(JobSendEmail, "email:"+n.ID, map[string]string{"notificationId": n.ID}, now)
 		if err != nil {
-			log.Printf("Error sending email to %s: %v", user.Email, err)
-			continue
+			return fmt.Errorf("enqueueing email for notification %s: %w", n.ID, err)
 		}
+	}
+	return nil
+}
+
+// sendEmail emails a notification to its user, unless it has been read or
+// emailed since the job was enqueued.
+func (w *Worker) sendEmail(ctx context.Context, job models.Job) error {
+	n, err := w.Store.GetNotification(job.Payload["notificationId"])
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if n.Read || n.Emailed {
+		return nil
+	}
+	user, err := w.Store.GetUser(n.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		return jobs.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	l := i18n.For(user)
+	err = email.SendNotificationEmail(ctx, w.Mailer, user.Email, l.Locale, l.Notification(n))
+	if err != nil {
+		return fmt.Errorf("sending email to %s: %w", user.Email, err)
+	}
 
-		// Mark as emailed so we don't send again
-		w.Store.MarkNotificationAsEmailed(n.ID)
-		log.Printf("Sent email for notification %s", n.ID)
+	// Mark as emailed so we don't send again
+	if err := w.Store.MarkNotificationAsEmailed(n.ID); err != nil {
+		log.Printf("Error marking notification %s as emailed: %v", n.ID, err)
 	}
+	log.Printf("Sent email for notification %s", n.ID)
+	return nil
 }